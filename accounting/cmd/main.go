@@ -37,11 +37,13 @@ func main() {
 		log.Fatal(err)
 	}
 
+	txManager := db.NewTxManager(conn)
 	accRepo := db.NewAccountRepo(conn)
 	audRepo := db.NewAuditRepo(conn)
+	processedEventRepo := db.NewProcessedEventRepo(conn)
 
 	mqClient := mq.NewMQClient(mqCfg)
-	srv, err := rest.NewServer(accRepo, audRepo, mqClient)
+	srv, err := rest.NewServer(txManager, accRepo, audRepo, processedEventRepo, mqClient)
 	if err != nil {
 		log.Fatal(err)
 	}
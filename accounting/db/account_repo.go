@@ -11,7 +11,7 @@ import (
 
 type (
 	AccountRepo struct {
-		db *sqlx.DB
+		db querier
 	}
 	Account struct {
 		ID         int       `db:"id"`
@@ -32,6 +32,13 @@ func NewAccountRepo(db *sqlx.DB) *AccountRepo {
 	}
 }
 
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *AccountRepo) WithTx(tx *sqlx.Tx) *AccountRepo {
+	return &AccountRepo{
+		db: tx,
+	}
+}
+
 func (r *AccountRepo) CreateRecord(ctx context.Context, acc *Account) (*Account, error) {
 	stmt, err := r.db.PrepareNamedContext(ctx,
 		`
@@ -10,7 +10,7 @@ import (
 
 type (
 	AuditRepo struct {
-		db *sqlx.DB
+		db querier
 	}
 	Audit struct {
 		ID         int       `db:"id"`
@@ -30,6 +30,13 @@ func NewAuditRepo(db *sqlx.DB) *AuditRepo {
 	}
 }
 
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *AuditRepo) WithTx(tx *sqlx.Tx) *AuditRepo {
+	return &AuditRepo{
+		db: tx,
+	}
+}
+
 func (r *AuditRepo) Create(ctx context.Context, aud *Audit) (*Audit, error) {
 	stmt, err := r.db.PrepareNamedContext(ctx,
 		`
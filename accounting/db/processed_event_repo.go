@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	ProcessedEventRepo struct {
+		db querier
+	}
+)
+
+func NewProcessedEventRepo(db *sqlx.DB) *ProcessedEventRepo {
+	return &ProcessedEventRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *ProcessedEventRepo) WithTx(tx *sqlx.Tx) *ProcessedEventRepo {
+	return &ProcessedEventRepo{
+		db: tx,
+	}
+}
+
+// MarkProcessed records the event as processed. It returns false if the event
+// has already been recorded, i.e. the event is a duplicate.
+func (r *ProcessedEventRepo) MarkProcessed(ctx context.Context, eventID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO processed_event(event_id) VALUES($1) ON CONFLICT (event_id) DO NOTHING`,
+		eventID,
+	)
+	if err != nil {
+		log.Printf("failed to mark event %s as processed: %v\n", eventID, err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
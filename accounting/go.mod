@@ -32,3 +32,5 @@ require (
 	golang.org/x/sys v0.0.0-20220811171246-fbc7d0a398ab // indirect
 	golang.org/x/text v0.3.7 // indirect
 )
+
+replace github.com/ko3luhbka/popug_schema_registry => ../popug_schema_registry
@@ -9,13 +9,23 @@ const (
 	kafkaHost = "localhost:29092"
 	GroupID   = "accountingConsumer"
 
+	// OutboxIDHeader carries the id of the task tracker outbox message a
+	// Kafka message was relayed from
+	OutboxIDHeader = "outboxId"
+
 	UsersCUDTopic    = "usersStreaming"
 	UserDeletedEvent = "userDeleted"
 
-	TasksTopic           = "tasks"
-	TaskAssignedEvent    = "taskAssigned"
-	TasksReassignedEvent = "tasksReassigned"
-	TaskCompletedEvent   = "taskCompleted"
+	TasksTopic             = "tasks"
+	TaskAssignedEvent      = "taskAssigned"
+	TasksReassignedEvent   = "tasksReassigned"
+	TaskCompletedEvent     = "taskCompleted"
+	TaskStatusChangedEvent = "taskStatusChanged"
+	TaskUpdatedEvent       = "taskUpdated"
+	TaskCommentedEvent     = "taskCommented"
+	TaskOverdueEvent       = "taskOverdue"
+	TaskDeletedEvent       = "taskDeleted"
+	TaskRestoredEvent      = "taskRestored"
 )
 
 type (
@@ -39,6 +49,11 @@ type (
 		Version int            `json:"version"`
 		Data    model.TaskInfo `json:"data"`
 	}
+	ReassignmentEvent struct {
+		Name    string                 `json:"name"`
+		Version int                    `json:"version"`
+		Data    model.ReassignmentInfo `json:"data"`
+	}
 )
 
 func NewMQClient(cfg *Config) *Client {
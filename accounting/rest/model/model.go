@@ -12,10 +12,22 @@ type (
 		Username string `json:"username"`
 	}
 	TaskInfo struct {
-		ID         string `json:"id"`
-		Title      string `json:"title"`
-		JiraID     string `json:"jira_id"`
-		AssigneeID string `json:"assignee_id"`
+		ID           string `json:"id"`
+		Title        string `json:"title"`
+		JiraID       string `json:"jira_id"`
+		AssigneeID   string `json:"assignee_id"`
+		AssignFee    int    `json:"assign_fee"`
+		CompleteCost int    `json:"complete_cost"`
+		// Priority and Estimate are carried since task schema version 9
+		Priority string `json:"priority"`
+		Estimate int    `json:"estimate"`
+	}
+	ReassignedTaskInfo struct {
+		TaskInfo
+		PreviousAssigneeID string `json:"previous_assignee_id"`
+	}
+	ReassignmentInfo struct {
+		Tasks []ReassignedTaskInfo `json:"tasks"`
 	}
 	Account struct {
 		ID         int       `json:"id"`
@@ -19,7 +19,13 @@ type Server struct {
 	app *fiber.App
 }
 
-func NewServer(tr *db.AccountRepo, ar *db.AuditRepo, mq *mq.Client) (*Server, error) {
+func NewServer(
+	txm *db.TxManager,
+	tr *db.AccountRepo,
+	ar *db.AuditRepo,
+	per *db.ProcessedEventRepo,
+	mq *mq.Client,
+) (*Server, error) {
 	var appCfg = fiber.Config{
 		CaseSensitive: true,
 		StrictRouting: false,
@@ -28,7 +34,7 @@ func NewServer(tr *db.AccountRepo, ar *db.AuditRepo, mq *mq.Client) (*Server, er
 	app := fiber.New(appCfg)
 	app.Use(logger.New())
 
-	svc := service.NewService(tr, ar, mq)
+	svc := service.NewService(txm, tr, ar, per, mq)
 
 	srv := &Server{
 		Svc: svc,
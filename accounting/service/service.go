@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/ko3luhbka/popug_schema_registry/validator"
 	"github.com/segmentio/kafka-go"
 
@@ -17,24 +18,50 @@ import (
 )
 
 const (
-	taskSchemaType    = "task"
-	endOfDayTimestamp = "18:00"
+	taskSchemaType         = "task"
+	reassignmentSchemaType = "tasks_reassigned"
+	endOfDayTimestamp      = "18:00"
+
+	// task events starting from this version carry the task prices
+	pricedTaskSchemaVersion = 3
+
+	// the assignee of an overdue task is withdrawn this percentage of the
+	// task assign fee, but at least one unit
+	overduePenaltyPercent = 50
 )
 
 type Service struct {
-	accountRepo *db.AccountRepo
-	auditRepo   *db.AuditRepo
-	Mq          *mq.Client
+	txManager          *db.TxManager
+	accountRepo        *db.AccountRepo
+	auditRepo          *db.AuditRepo
+	processedEventRepo *db.ProcessedEventRepo
+	Mq                 *mq.Client
 }
 
-func NewService(accr *db.AccountRepo, audr *db.AuditRepo, mq *mq.Client) *Service {
+func NewService(
+	txm *db.TxManager,
+	accr *db.AccountRepo,
+	audr *db.AuditRepo,
+	per *db.ProcessedEventRepo,
+	mq *mq.Client,
+) *Service {
 	return &Service{
-		accountRepo: accr,
-		auditRepo:   audr,
-		Mq:          mq,
+		txManager:          txm,
+		accountRepo:        accr,
+		auditRepo:          audr,
+		processedEventRepo: per,
+		Mq:                 mq,
 	}
 }
 
+// withTx returns a copy of the service whose repos run their queries in tx.
+func (s Service) withTx(tx *sqlx.Tx) Service {
+	s.accountRepo = s.accountRepo.WithTx(tx)
+	s.auditRepo = s.auditRepo.WithTx(tx)
+	s.processedEventRepo = s.processedEventRepo.WithTx(tx)
+	return s
+}
+
 func (s Service) RunWorkdayTimer(ctx context.Context, done chan bool) error {
 	const timeLayout = "15:04"
 	untilTime, err := time.Parse(timeLayout, endOfDayTimestamp)
@@ -109,10 +136,10 @@ func (s Service) DeleteUserAccount(ctx context.Context, uuid string) error {
 	return s.accountRepo.DeleteByUser(ctx, uuid)
 }
 
-func (s Service) PayToUser(ctx context.Context, uuid string) (int, error) {
+func (s Service) PayToUser(ctx context.Context, uuid string, amount int) (int, error) {
 	acc := &db.Account{
 		AssigneeID: uuid,
-		Debit:      getRandNumInRange(20, 40),
+		Debit:      amount,
 	}
 	created, err := s.accountRepo.CreateRecord(ctx, acc)
 	if err != nil {
@@ -121,10 +148,10 @@ func (s Service) PayToUser(ctx context.Context, uuid string) (int, error) {
 	return created.Debit, nil
 }
 
-func (s Service) WithdrawUser(ctx context.Context, uuid string) (int, error) {
+func (s Service) WithdrawUser(ctx context.Context, uuid string, amount int) (int, error) {
 	acc := &db.Account{
 		AssigneeID: uuid,
-		Credit:     -getRandNumInRange(10, 20),
+		Credit:     -amount,
 	}
 	created, err := s.accountRepo.CreateRecord(ctx, acc)
 	if err != nil {
@@ -141,6 +168,26 @@ func (s Service) GetManagementIncome(ctx context.Context) (int, error) {
 	return income, nil
 }
 
+// taskPrices returns the assign fee and the completion reward of the task.
+// Events older than pricedTaskSchemaVersion don't carry prices, so they are
+// still rolled here to keep such events processable.
+func taskPrices(e *mq.TaskEvent) (assignFee, completeCost int) {
+	if e.Version < pricedTaskSchemaVersion {
+		return getRandNumInRange(10, 20), getRandNumInRange(20, 40)
+	}
+	return e.Data.AssignFee, e.Data.CompleteCost
+}
+
+// overduePenalty returns the penalty for letting a task with the given assign
+// fee become overdue.
+func overduePenalty(assignFee int) int {
+	penalty := assignFee * overduePenaltyPercent / 100
+	if penalty < 1 {
+		return 1
+	}
+	return penalty
+}
+
 func getRandNumInRange(min, max int) int {
 	rand.Seed(time.Now().UnixNano())
 	return rand.Intn(max-min) + min
@@ -200,7 +247,7 @@ func (s Service) handleEvent(ctx context.Context, msg *kafka.Message) error {
 	case mq.UsersCUDTopic:
 		return s.handleUserEvents(ctx, msg)
 	case mq.TasksTopic:
-		return s.handleTaskEvents(ctx, msg)
+		return s.handleTaskMessage(ctx, msg)
 	default:
 		return fmt.Errorf("unknownn topic: %s", msg.Topic)
 	}
@@ -225,20 +272,63 @@ func (s Service) handleUserEvents(ctx context.Context, msg *kafka.Message) error
 	}
 }
 
+// handleTaskMessage handles a task event at most once. The task tracker may
+// relay the same outbox message again, e.g. when it fails to mark the message
+// as sent, so the event is recorded as processed in the same transaction as
+// the balance changes it causes, and the repeated ones are skipped.
+func (s Service) handleTaskMessage(ctx context.Context, msg *kafka.Message) error {
+	eventID := outboxID(msg)
+	if eventID == "" {
+		return s.handleTaskEvents(ctx, msg)
+	}
+	return s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		txs := s.withTx(tx)
+		isNew, err := txs.processedEventRepo.MarkProcessed(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			log.Printf("skipping already processed task event %s\n", eventID)
+			return nil
+		}
+		return txs.handleTaskEvents(ctx, msg)
+	})
+}
+
+// outboxID returns the id of the outbox message the Kafka message was relayed
+// from, or an empty string if the message has none.
+func outboxID(msg *kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == mq.OutboxIDHeader {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
 func (s Service) handleTaskEvents(ctx context.Context, msg *kafka.Message) error {
 	var e mq.TaskEvent
 	if err := json.Unmarshal(msg.Value, &e); err != nil {
 		return err
 	}
+	if e.Name == mq.TasksReassignedEvent {
+		return s.handleReassignmentEvent(msg, e.Version)
+	}
+	if e.Name == mq.TaskCommentedEvent {
+		// comments don't affect balances
+		return nil
+	}
 
-	if err := validator.Validate(e, taskSchemaType, 2); err != nil {
+	// validate the raw message, so fields unknown to this service are checked too
+	if err := validator.Validate(json.RawMessage(msg.Value), taskSchemaType, e.Version); err != nil {
 		return fmt.Errorf("invalid event: %v", err)
 	}
 
 	user := e.Data.AssigneeID
+	assignFee, completeCost := taskPrices(&e)
 	switch e.Name {
 	case mq.TaskAssignedEvent:
-		amount, err := s.WithdrawUser(ctx, user)
+		amount, err := s.WithdrawUser(ctx, user, assignFee)
 		if err != nil {
 			return fmt.Errorf("failed to withdraw user %s: %v", user, err)
 		}
@@ -257,7 +347,7 @@ func (s Service) handleTaskEvents(ctx context.Context, msg *kafka.Message) error
 		log.Printf("user %s was withdrawed due to assigned task", user)
 		return nil
 	case mq.TaskCompletedEvent:
-		amount, err := s.PayToUser(ctx, user)
+		amount, err := s.PayToUser(ctx, user, completeCost)
 		if err != nil {
 			return fmt.Errorf("failed to pay to user %s: %v", user, err)
 		}
@@ -275,7 +365,63 @@ func (s Service) handleTaskEvents(ctx context.Context, msg *kafka.Message) error
 		}
 		log.Printf("user %s was payed due to completed task", user)
 		return nil
+	case mq.TaskStatusChangedEvent, mq.TaskUpdatedEvent:
+		// balances only change on assignment & completion, which have their own events
+		return nil
+	case mq.TaskOverdueEvent:
+		amount, err := s.WithdrawUser(ctx, user, overduePenalty(assignFee))
+		if err != nil {
+			return fmt.Errorf("failed to withdraw user %s: %v", user, err)
+		}
+		audit := &db.Audit{
+			EventName:  mq.TaskOverdueEvent,
+			AssigneeID: user,
+			TaskID:     e.Data.ID,
+			TaskTitle:  e.Data.Title,
+			JiraID:     e.Data.JiraID,
+			Amount:     amount,
+		}
+		if _, err := s.CreateAuditRecord(ctx, audit); err != nil {
+			return err
+		}
+		log.Printf("user %s was withdrawed a penalty due to overdue task %s", user, e.Data.ID)
+		return nil
+	case mq.TaskDeletedEvent, mq.TaskRestoredEvent:
+		// balances stay the same, but the audit log should tell that the task is gone or back
+		audit := &db.Audit{
+			EventName:  e.Name,
+			AssigneeID: user,
+			TaskID:     e.Data.ID,
+			TaskTitle:  e.Data.Title,
+			JiraID:     e.Data.JiraID,
+		}
+		if _, err := s.CreateAuditRecord(ctx, audit); err != nil {
+			return err
+		}
+		log.Printf("task %s got %s", e.Data.ID, e.Name)
+		return nil
 	default:
 		return fmt.Errorf("unknown event name: %v", e.Name)
 	}
 }
+
+// handleReassignmentEvent only reports the reassignment batch: every reassigned
+// task also comes as a separate taskAssigned event, which the assignee is
+// charged on.
+func (s Service) handleReassignmentEvent(msg *kafka.Message, version int) error {
+	if err := validator.Validate(json.RawMessage(msg.Value), reassignmentSchemaType, version); err != nil {
+		return fmt.Errorf("invalid event: %v", err)
+	}
+
+	var e mq.ReassignmentEvent
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		return err
+	}
+
+	total := 0
+	for _, t := range e.Data.Tasks {
+		total += t.AssignFee
+	}
+	log.Printf("%d tasks were reassigned, %d to be withdrawn in total", len(e.Data.Tasks), total)
+	return nil
+}
@@ -8,6 +8,7 @@ require (
 	github.com/golang-jwt/jwt/v4 v4.4.2
 	github.com/jackc/pgx/v4 v4.17.0
 	github.com/jmoiron/sqlx v1.3.5
+	github.com/ko3luhbka/popug_schema_registry v0.0.0-20221022100944-1869d03f5904
 	github.com/pressly/goose/v3 v3.7.0
 	github.com/segmentio/kafka-go v0.4.35
 	golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8
@@ -44,3 +45,5 @@ require (
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
 )
+
+replace github.com/ko3luhbka/popug_schema_registry => ../popug_schema_registry
@@ -109,6 +109,11 @@ func (s Server) updateUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := u.ValidateUpdate(); err != nil {
+		log.Printf("invalid user: %v\n", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	u.ID = uuid
 
 	updated, err := s.repo.Update(r.Context(), *u.ToEntity())
@@ -119,7 +124,7 @@ func (s Server) updateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	e := &mq.UserEvent{
-		Name: mq.UserCreatedEvent,
+		Name: mq.UserUpdatedEvent,
 		Data: model.EntityToAssignee(updated),
 	}
 	if err := s.mq.Produce(r.Context(), e); err != nil {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ko3luhbka/auth/db"
+	"github.com/ko3luhbka/popug_schema_registry/roles"
 )
 
 type (
@@ -23,6 +24,8 @@ type (
 	Assignee struct {
 		ID       string `json:"id"`
 		Username string `json:"username"`
+		Role     string `json:"role,omitempty"`
+		Email    string `json:"email,omitempty"`
 	}
 )
 
@@ -30,6 +33,8 @@ func EntityToAssignee(e *db.User) *Assignee {
 	return &Assignee{
 		ID:       e.ID,
 		Username: e.Username,
+		Role:     e.Role,
+		Email:    e.Email,
 	}
 }
 
@@ -62,6 +67,21 @@ func (u *User) Validate() error {
 	if u.Role == "" {
 		return fmt.Errorf("role field is empty")
 	}
+	return u.validateRole()
+}
+
+// ValidateUpdate lets the fields be left empty to keep them as they are.
+func (u *User) ValidateUpdate() error {
+	if u.Role == "" {
+		return nil
+	}
+	return u.validateRole()
+}
+
+func (u *User) validateRole() error {
+	if !roles.IsKnown(u.Role) {
+		return fmt.Errorf("unknown role: %s", u.Role)
+	}
 	return nil
 }
 
@@ -0,0 +1,24 @@
+// Package roles defines the user roles auth hands out and the other services
+// authorize by, so that all of them agree on the role names.
+package roles
+
+const (
+	Admin      = "admin"
+	Manager    = "manager"
+	Accountant = "accountant"
+	// Worker is the only role tasks are assigned to.
+	Worker = "worker"
+)
+
+var known = map[string]struct{}{
+	Admin:      {},
+	Manager:    {},
+	Accountant: {},
+	Worker:     {},
+}
+
+// IsKnown reports whether the role is one of the roles defined here.
+func IsKnown(role string) bool {
+	_, ok := known[role]
+	return ok
+}
@@ -4,5 +4,5 @@ import (
 	"embed"
 )
 
-//go:embed versions/task/*.json
+//go:embed versions
 var SchemaFS embed.FS
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -9,10 +10,15 @@ import (
 	"github.com/ko3luhbka/task_tracker/migrations"
 	"github.com/ko3luhbka/task_tracker/mq"
 	"github.com/ko3luhbka/task_tracker/rest"
+	"github.com/ko3luhbka/task_tracker/service"
+	"github.com/ko3luhbka/task_tracker/storage"
 )
 
 const (
 	serviceName = "task_tracker"
+	// defaultAttachmentsDir is where the attachment contents are stored
+	// unless ATTACHMENTS_DIR is set
+	defaultAttachmentsDir = "attachments"
 )
 
 var (
@@ -20,10 +26,24 @@ var (
 		Consumer:   true,
 		Producer:   true,
 		ReadTopic:  mq.UsersCUDTopic,
-		WriteTopic: mq.TasksTopic,
+		// left empty on purpose: the topic is taken from each outbox message
+		WriteTopic: "",
+	}
+	svcCfg = &service.Config{
+		// one of random, round_robin, least_loaded or weighted
+		AssignmentStrategy: envOrDefault("ASSIGNMENT_STRATEGY", service.RandomStrategy),
 	}
 )
 
+// envOrDefault returns the value of the environment variable or def if it's
+// not set.
+func envOrDefault(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
 func main() {
 	log.Printf("Starting %s service", serviceName)
 
@@ -36,11 +56,33 @@ func main() {
 		log.Fatal(err)
 	}
 
-	taskRepo := db.NewTaskRepo(conn)
-	assigneeRepo := db.NewAssigneeepo(conn)
+	blobs, err := storage.NewLocalStore(envOrDefault("ATTACHMENTS_DIR", defaultAttachmentsDir))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	repos := &service.Repos{
+		TxManager:    db.NewTxManager(conn),
+		Task:         db.NewTaskRepo(conn),
+		Assignee:     db.NewAssigneeepo(conn),
+		Outbox:       db.NewOutboxRepo(conn),
+		Plan:         db.NewReassignmentPlanRepo(conn),
+		Comment:      db.NewCommentRepo(conn),
+		History:      db.NewHistoryRepo(conn),
+		Label:        db.NewLabelRepo(conn),
+		Link:         db.NewLinkRepo(conn),
+		Idempotency:  db.NewIdempotencyRepo(conn),
+		Webhook:      db.NewWebhookRepo(conn),
+		Feed:         db.NewFeedRepo(conn),
+		Attachment:   db.NewAttachmentRepo(conn),
+		Blobs:        blobs,
+		Watcher:      db.NewWatcherRepo(conn),
+		Notification: db.NewNotificationRepo(conn),
+		Project:      db.NewProjectRepo(conn),
+	}
 
 	mqClient := mq.NewMQClient(mqCfg)
-	srv, err := rest.NewServer(taskRepo, assigneeRepo, mqClient)
+	srv, err := rest.NewServer(svcCfg, repos, mqClient)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -48,19 +90,25 @@ func main() {
 	errCh := make(chan error)
 	srv.Run(errCh)
 	srv.Svc.ConsumeMsg(errCh)
+	done := make(chan bool)
+	srv.Svc.RunOutboxRelay(context.Background(), done)
+	srv.Svc.RunOverdueChecker(context.Background(), done)
+	srv.Svc.RunPurgeJob(context.Background(), done)
+	srv.Svc.RunWebhookDispatcher(context.Background(), done)
 
 	exitCh := make(chan os.Signal, 1)
 	signal.Notify(exitCh, os.Interrupt)
 
 	select {
 	case <-exitCh:
-		shutdown(srv)
+		shutdown(srv, done)
 	case <-errCh:
-		shutdown(srv)
+		shutdown(srv, done)
 	}
 }
 
-func shutdown(srv *rest.Server) {
+func shutdown(srv *rest.Server, done chan bool) {
+	close(done)
 	if err := srv.Shutdown(); err != nil {
 		log.Println(err)
 	}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/ko3luhbka/popug_schema_registry/roles"
 )
 
 type (
@@ -16,7 +17,15 @@ type (
 	Assignee struct {
 		ID       string `db:"id"`
 		Username string `db:"username"`
-		Role string `db:"role"`
+		Role     string `db:"role"`
+		Email    string `db:"email"`
+		Capacity int    `db:"capacity"`
+	}
+	// Worker is an assignee tasks can be assigned to, along with the number
+	// of tasks it currently has open.
+	Worker struct {
+		Assignee
+		OpenTasks int `db:"open_tasks"`
 	}
 )
 
@@ -31,12 +40,19 @@ func (r *AssigneeRepo) Create(ctx context.Context, a Assignee) (*Assignee, error
 		`
 		INSERT INTO assignee (
 				id,
-				username)
+				username,
+				role,
+				email)
 		VALUES(:id,
-				:username)
+				:username,
+				:role,
+				:email)
 		RETURNING
 				id,
-				username`,
+				username,
+				role,
+				email,
+				capacity`,
 	)
 	if err != nil {
 		log.Printf("failed to prepare assignee create query: %v\n", err)
@@ -55,7 +71,10 @@ func (r *AssigneeRepo) GetAll(ctx context.Context) ([]Assignee, error) {
 	err := r.db.SelectContext(
 		ctx, &assignees, `
 		SELECT 	id,
-				username
+				username,
+				role,
+				email,
+				capacity
 		FROM assignee`,
 	)
 	if err != nil {
@@ -65,6 +84,31 @@ func (r *AssigneeRepo) GetAll(ctx context.Context) ([]Assignee, error) {
 	return assignees, nil
 }
 
+// GetWorkers returns assignees having the role tasks may be assigned to,
+// sorted by ID. Assignees whose role auth hasn't reported yet are left out.
+func (r *AssigneeRepo) GetWorkers(ctx context.Context) ([]Worker, error) {
+	var workers []Worker
+	err := r.db.SelectContext(
+		ctx, &workers, `
+		SELECT 	a.id,
+				a.username,
+				a.role,
+				a.email,
+				a.capacity,
+				count(t.id) AS open_tasks
+		FROM assignee a
+		LEFT JOIN task t ON t.assignee_id=a.id AND t.status<>'Completed' AND t.deleted_at IS NULL
+		WHERE a.role=$1
+		GROUP BY a.id
+		ORDER BY a.id`, roles.Worker,
+	)
+	if err != nil {
+		log.Printf("failed to get workers: %v\n", err)
+		return nil, err
+	}
+	return workers, nil
+}
+
 func (r *AssigneeRepo) Update(ctx context.Context, a Assignee) (*Assignee, error) {
 	stmt, err := r.db.PrepareNamedContext(ctx, buildUpdateQuery(&a))
 	if err != nil {
@@ -86,11 +130,23 @@ func buildUpdateQuery(a *Assignee) string {
 	if a.Username != "" {
 		queryBuilder.WriteString(`username=:username, `)
 	}
+	if a.Role != "" {
+		queryBuilder.WriteString(`role=:role, `)
+	}
+	if a.Email != "" {
+		queryBuilder.WriteString(`email=:email, `)
+	}
+	if a.Capacity != 0 {
+		queryBuilder.WriteString(`capacity=:capacity, `)
+	}
 	queryBuilder.WriteString(`id=:id `)
 	queryBuilder.WriteString(`WHERE id=:id `)
 	queryBuilder.WriteString(`RETURNING
 		id,
-		username`)
+		username,
+		role,
+		email,
+		capacity`)
 	return queryBuilder.String()
 }
 
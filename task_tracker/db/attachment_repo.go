@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	AttachmentRepo struct {
+		db querier
+	}
+	// Attachment is the metadata of a file attached to a task. The content
+	// itself is kept in the blob storage under StorageKey.
+	Attachment struct {
+		ID          string    `db:"id"`
+		TaskID      string    `db:"task_id"`
+		Filename    string    `db:"filename"`
+		ContentType string    `db:"content_type"`
+		Size        int64     `db:"size"`
+		Checksum    string    `db:"checksum"`
+		StorageKey  string    `db:"storage_key"`
+		UploaderID  string    `db:"uploader_id"`
+		Created     time.Time `db:"created"`
+	}
+)
+
+func NewAttachmentRepo(db *sqlx.DB) *AttachmentRepo {
+	return &AttachmentRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *AttachmentRepo) WithTx(tx *sqlx.Tx) *AttachmentRepo {
+	return &AttachmentRepo{
+		db: tx,
+	}
+}
+
+func (r *AttachmentRepo) Create(ctx context.Context, a Attachment) (*Attachment, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO task_attachment(
+				task_id,
+				filename,
+				content_type,
+				size,
+				checksum,
+				storage_key,
+				uploader_id,
+				created)
+		VALUES(:task_id,
+				:filename,
+				:content_type,
+				:size,
+				:checksum,
+				:storage_key,
+				:uploader_id,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				task_id,
+				filename,
+				content_type,
+				size,
+				checksum,
+				storage_key,
+				uploader_id,
+				created`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare attachment create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &a, a)
+	if err != nil {
+		log.Printf("failed to create attachment of task %s: %v\n", a.TaskID, err)
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AttachmentRepo) GetByTask(ctx context.Context, taskID string) ([]Attachment, error) {
+	attachments := []Attachment{}
+	err := r.db.SelectContext(
+		ctx, &attachments, `
+		SELECT 	id,
+				task_id,
+				filename,
+				content_type,
+				size,
+				checksum,
+				storage_key,
+				uploader_id,
+				created
+		FROM task_attachment
+		WHERE task_id=$1
+		ORDER BY created, id`, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get attachments of task %s: %v\n", taskID, err)
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *AttachmentRepo) GetByID(ctx context.Context, taskID, uuid string) (*Attachment, error) {
+	var a Attachment
+	err := r.db.GetContext(
+		ctx, &a, `
+		SELECT 	id,
+				task_id,
+				filename,
+				content_type,
+				size,
+				checksum,
+				storage_key,
+				uploader_id,
+				created
+		FROM task_attachment
+		WHERE id=$1 AND task_id=$2`, uuid, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get attachment with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AttachmentRepo) Delete(ctx context.Context, uuid string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_attachment WHERE id=$1;`, uuid)
+	if err != nil {
+		log.Printf("failed to delete attachment with id %s: %v\n", uuid, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteOfDeletedTasks removes the attachments of the tasks soft deleted
+// before the given time and returns where their contents are stored. The
+// tasks are locked, so that they can't be restored until the transaction
+// purging them ends. It must be called within WithTx.
+func (r *AttachmentRepo) DeleteOfDeletedTasks(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	keys := []string{}
+	err := r.db.SelectContext(
+		ctx, &keys, `
+		DELETE FROM task_attachment
+		WHERE task_id IN (
+			SELECT id
+			FROM task
+			WHERE deleted_at<$1
+			FOR UPDATE
+		)
+		RETURNING storage_key`, deletedBefore,
+	)
+	if err != nil {
+		log.Printf("failed to delete attachments of deleted tasks: %v\n", err)
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	CommentRepo struct {
+		db querier
+	}
+	Comment struct {
+		ID       string     `db:"id"`
+		TaskID   string     `db:"task_id"`
+		AuthorID string     `db:"author_id"`
+		Body     string     `db:"body"`
+		Created  time.Time  `db:"created"`
+		Updated  *time.Time `db:"updated"`
+	}
+	// CommentEdit keeps the body a comment had before it was edited.
+	CommentEdit struct {
+		ID        int64     `db:"id"`
+		CommentID string    `db:"comment_id"`
+		Body      string    `db:"body"`
+		EditorID  string    `db:"editor_id"`
+		Edited    time.Time `db:"edited"`
+	}
+)
+
+func NewCommentRepo(db *sqlx.DB) *CommentRepo {
+	return &CommentRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *CommentRepo) WithTx(tx *sqlx.Tx) *CommentRepo {
+	return &CommentRepo{
+		db: tx,
+	}
+}
+
+func (r *CommentRepo) Create(ctx context.Context, c Comment) (*Comment, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO task_comment(
+				task_id,
+				author_id,
+				body,
+				created)
+		VALUES(:task_id,
+				:author_id,
+				:body,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				task_id,
+				author_id,
+				body,
+				created,
+				updated`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare comment create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &c, c)
+	if err != nil {
+		log.Printf("failed to create comment: %v\n", err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CommentRepo) GetByTask(ctx context.Context, taskID string) ([]Comment, error) {
+	comments := []Comment{}
+	err := r.db.SelectContext(
+		ctx, &comments, `
+		SELECT 	id,
+				task_id,
+				author_id,
+				body,
+				created,
+				updated
+		FROM task_comment
+		WHERE task_id=$1
+		ORDER BY created, id`, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get comments of task %s: %v\n", taskID, err)
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetByID returns the comment of the given task.
+func (r *CommentRepo) GetByID(ctx context.Context, taskID, uuid string) (*Comment, error) {
+	var c Comment
+	err := r.db.GetContext(
+		ctx, &c, `
+		SELECT  id,
+				task_id,
+				author_id,
+				body,
+				created,
+				updated
+		FROM task_comment
+		WHERE id=$1 AND task_id=$2`, uuid, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get comment with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetByIDForUpdate returns the comment of the given task locking it until the
+// surrounding transaction ends, so it must be called within WithTx.
+func (r *CommentRepo) GetByIDForUpdate(ctx context.Context, taskID, uuid string) (*Comment, error) {
+	var c Comment
+	err := r.db.GetContext(
+		ctx, &c, `
+		SELECT  id,
+				task_id,
+				author_id,
+				body,
+				created,
+				updated
+		FROM task_comment
+		WHERE id=$1 AND task_id=$2
+		FOR UPDATE`, uuid, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get comment with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CommentRepo) UpdateBody(ctx context.Context, uuid, body string) (*Comment, error) {
+	var c Comment
+	err := r.db.GetContext(
+		ctx, &c, `
+		UPDATE task_comment
+		SET body=$2,
+			updated=CURRENT_TIMESTAMP
+		WHERE id=$1
+		RETURNING
+				id,
+				task_id,
+				author_id,
+				body,
+				created,
+				updated`, uuid, body,
+	)
+	if err != nil {
+		log.Printf("failed to update comment with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CommentRepo) CreateEdit(ctx context.Context, e CommentEdit) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO task_comment_edit(
+				comment_id,
+				body,
+				editor_id,
+				edited)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP)`,
+		e.CommentID, e.Body, e.EditorID,
+	)
+	if err != nil {
+		log.Printf("failed to create edit of comment %s: %v\n", e.CommentID, err)
+		return err
+	}
+	return nil
+}
+
+func (r *CommentRepo) GetEdits(ctx context.Context, taskID, commentID string) ([]CommentEdit, error) {
+	edits := []CommentEdit{}
+	err := r.db.SelectContext(
+		ctx, &edits, `
+		SELECT 	e.id,
+				e.comment_id,
+				e.body,
+				e.editor_id,
+				e.edited
+		FROM task_comment_edit e
+		JOIN task_comment c ON c.id=e.comment_id
+		WHERE e.comment_id=$1 AND c.task_id=$2
+		ORDER BY e.edited, e.id`, commentID, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get edits of comment %s: %v\n", commentID, err)
+		return nil, err
+	}
+	return edits, nil
+}
+
+func (r *CommentRepo) Delete(ctx context.Context, uuid string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM task_comment WHERE id=$1;`, uuid)
+	if err != nil {
+		log.Printf("failed to delete comment with id %s: %v\n", uuid, err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no comment found with uuid %s", uuid)
+	}
+
+	return nil
+}
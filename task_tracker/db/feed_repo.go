@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	FeedRepo struct {
+		db querier
+	}
+	// FeedPosition points at a feed entry. Entries are ordered by the ID of
+	// the transaction which added them first, since their own IDs are taken
+	// before the transactions commit and may become visible out of order.
+	FeedPosition struct {
+		TxID uint64 `db:"txid"`
+		ID   int64  `db:"id"`
+	}
+	// TaskFeedEntry is a change of a task streamed to the board clients.
+	TaskFeedEntry struct {
+		ID                 int64  `db:"id"`
+		TxID               uint64 `db:"txid"`
+		Kind               string `db:"kind"`
+		TaskID             string `db:"task_id"`
+		AssigneeID         string `db:"assignee_id"`
+		PreviousAssigneeID string `db:"previous_assignee_id"`
+		ProjectID          string `db:"project_id"`
+		// Payload is the task as of the change
+		Payload []byte    `db:"payload"`
+		Created time.Time `db:"created"`
+	}
+)
+
+func NewFeedRepo(db *sqlx.DB) *FeedRepo {
+	return &FeedRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *FeedRepo) WithTx(tx *sqlx.Tx) *FeedRepo {
+	return &FeedRepo{
+		db: tx,
+	}
+}
+
+func (r *FeedRepo) Create(ctx context.Context, e TaskFeedEntry) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO task_feed(
+				kind,
+				task_id,
+				assignee_id,
+				previous_assignee_id,
+				project_id,
+				payload,
+				created)
+		VALUES($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, $6, CURRENT_TIMESTAMP)`,
+		e.Kind, e.TaskID, e.AssigneeID, e.PreviousAssigneeID, e.ProjectID, e.Payload,
+	)
+	if err != nil {
+		log.Printf("failed to add task %s to feed: %v\n", e.TaskID, err)
+		return err
+	}
+	return nil
+}
+
+// Position returns the position a client following the feed from now on
+// starts after. Entries of the transactions still in progress are yet to be
+// followed, so the position is right before the oldest of them.
+func (r *FeedRepo) Position(ctx context.Context) (*FeedPosition, error) {
+	var p FeedPosition
+	err := r.db.GetContext(
+		ctx, &p, `
+		SELECT 	pg_snapshot_xmin(pg_current_snapshot())::text AS txid,
+				0 AS id`,
+	)
+	if err != nil {
+		log.Printf("failed to get task feed position: %v\n", err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetAfter returns up to limit entries following the given position. Only the
+// entries of the transactions older than any transaction still in progress
+// are returned, so no entry can show up before the returned ones later on.
+// Unless assigneeID is empty, only the entries of the tasks which are or used
+// to be assigned to it are returned. Likewise, only the entries of the
+// project tasks are returned unless projectID is empty.
+func (r *FeedRepo) GetAfter(ctx context.Context, after FeedPosition, assigneeID, projectID string, limit int) ([]TaskFeedEntry, error) {
+	entries := []TaskFeedEntry{}
+	err := r.db.SelectContext(
+		ctx, &entries, `
+		SELECT 	id,
+				txid::text AS txid,
+				kind,
+				task_id,
+				assignee_id,
+				COALESCE(previous_assignee_id::text, '') AS previous_assignee_id,
+				COALESCE(project_id::text, '') AS project_id,
+				payload,
+				created
+		FROM task_feed
+		WHERE (txid, id)>($1::xid8, $2)
+			AND txid<pg_snapshot_xmin(pg_current_snapshot())
+			AND ($3::text='' OR assignee_id=NULLIF($3, '')::uuid OR previous_assignee_id=NULLIF($3, '')::uuid)
+			AND ($4::text='' OR project_id=NULLIF($4, '')::uuid)
+		ORDER BY txid, id
+		LIMIT $5`, strconv.FormatUint(after.TxID, 10), after.ID, assigneeID, projectID, limit,
+	)
+	if err != nil {
+		log.Printf("failed to get task feed after %d-%d: %v\n", after.TxID, after.ID, err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteBefore removes the entries created before the given time and returns
+// the number of removed entries.
+func (r *FeedRepo) DeleteBefore(ctx context.Context, createdBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM task_feed WHERE created<$1;`, createdBefore)
+	if err != nil {
+		log.Printf("failed to delete old task feed entries: %v\n", err)
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return 0, err
+	}
+	return affected, nil
+}
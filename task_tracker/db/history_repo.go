@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	HistoryRepo struct {
+		db querier
+	}
+	// TaskChange is a change of a single task field.
+	TaskChange struct {
+		ID       int64     `db:"id"`
+		TaskID   string    `db:"task_id"`
+		Field    string    `db:"field"`
+		OldValue string    `db:"old_value"`
+		NewValue string    `db:"new_value"`
+		ActorID  string    `db:"actor_id"`
+		Changed  time.Time `db:"changed"`
+	}
+)
+
+func NewHistoryRepo(db *sqlx.DB) *HistoryRepo {
+	return &HistoryRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *HistoryRepo) WithTx(tx *sqlx.Tx) *HistoryRepo {
+	return &HistoryRepo{
+		db: tx,
+	}
+}
+
+func (r *HistoryRepo) Create(ctx context.Context, changes []TaskChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO task_history(
+				task_id,
+				field,
+				old_value,
+				new_value,
+				actor_id,
+				changed)
+		VALUES(:task_id,
+				:field,
+				:old_value,
+				:new_value,
+				:actor_id,
+				CURRENT_TIMESTAMP)`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare task history create query: %v\n", err)
+		return err
+	}
+	for _, c := range changes {
+		if _, err := stmt.ExecContext(ctx, c); err != nil {
+			log.Printf("failed to record change of task %s: %v\n", c.TaskID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HistoryRepo) GetByTask(ctx context.Context, taskID string) ([]TaskChange, error) {
+	changes := []TaskChange{}
+	err := r.db.SelectContext(
+		ctx, &changes, `
+		SELECT 	id,
+				task_id,
+				field,
+				old_value,
+				new_value,
+				actor_id,
+				changed
+		FROM task_history
+		WHERE task_id=$1
+		ORDER BY changed, id`, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get history of task %s: %v\n", taskID, err)
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	IdempotencyRepo struct {
+		db querier
+	}
+	// IdempotencyKey stores the response to the first request made with the
+	// key, so that it can be replayed for the retries. StatusCode is nil while
+	// the first request is being handled, which it's given until LockedUntil.
+	IdempotencyKey struct {
+		Key         string    `db:"key"`
+		Endpoint    string    `db:"endpoint"`
+		RequestHash string    `db:"request_hash"`
+		StatusCode  *int      `db:"status_code"`
+		ContentType *string   `db:"content_type"`
+		Response    []byte    `db:"response"`
+		LockedUntil time.Time `db:"locked_until"`
+		Created     time.Time `db:"created"`
+	}
+)
+
+func NewIdempotencyRepo(db *sqlx.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		db: db,
+	}
+}
+
+// Reserve stores the key unless it's already there. It reports whether the
+// key has been stored, i.e. the request is the first one with the key. The
+// key is leased to the request for the lease duration. Keys older than ttl
+// and keys whose lease is over with no response saved are replaced as if they
+// were not there.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, k IdempotencyKey, ttl, lease time.Duration) (bool, error) {
+	var key string
+	err := r.db.GetContext(
+		ctx, &key, `
+		INSERT INTO idempotency_key(
+				key,
+				endpoint,
+				request_hash,
+				locked_until,
+				created)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $5), CURRENT_TIMESTAMP)
+		ON CONFLICT (key, endpoint) DO UPDATE
+		SET request_hash=EXCLUDED.request_hash,
+			status_code=NULL,
+			content_type=NULL,
+			response=NULL,
+			locked_until=EXCLUDED.locked_until,
+			created=EXCLUDED.created
+		WHERE idempotency_key.created<CURRENT_TIMESTAMP - make_interval(secs => $4)
+			OR (idempotency_key.status_code IS NULL AND idempotency_key.locked_until<CURRENT_TIMESTAMP)
+		RETURNING key`, k.Key, k.Endpoint, k.RequestHash, ttl.Seconds(), lease.Seconds(),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("failed to reserve idempotency key %s: %v\n", k.Key, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *IdempotencyRepo) Get(ctx context.Context, key, endpoint string) (*IdempotencyKey, error) {
+	var k IdempotencyKey
+	err := r.db.GetContext(
+		ctx, &k, `
+		SELECT 	key,
+				endpoint,
+				request_hash,
+				status_code,
+				content_type,
+				response,
+				locked_until,
+				created
+		FROM idempotency_key
+		WHERE key=$1 AND endpoint=$2`, key, endpoint,
+	)
+	if err != nil {
+		log.Printf("failed to get idempotency key %s: %v\n", key, err)
+		return nil, err
+	}
+	return &k, nil
+}
+
+// SaveResponse stores the response to the request the key has been reserved for.
+func (r *IdempotencyRepo) SaveResponse(ctx context.Context, k IdempotencyKey) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_key
+		SET status_code=$3,
+			content_type=$4,
+			response=$5
+		WHERE key=$1 AND endpoint=$2`,
+		k.Key, k.Endpoint, k.StatusCode, k.ContentType, k.Response,
+	)
+	if err != nil {
+		log.Printf("failed to save response for idempotency key %s: %v\n", k.Key, err)
+		return err
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) Delete(ctx context.Context, key, endpoint string) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM idempotency_key WHERE key=$1 AND endpoint=$2;`, key, endpoint,
+	)
+	if err != nil {
+		log.Printf("failed to delete idempotency key %s: %v\n", key, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired removes the keys created before the given time and returns
+// the number of removed keys.
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context, createdBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_key WHERE created<$1;`, createdBefore)
+	if err != nil {
+		log.Printf("failed to delete expired idempotency keys: %v\n", err)
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return 0, err
+	}
+	return affected, nil
+}
@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	LabelRepo struct {
+		db querier
+	}
+	Label struct {
+		ID      string    `db:"id"`
+		Name    string    `db:"name"`
+		Color   string    `db:"color"`
+		Created time.Time `db:"created"`
+	}
+	taskLabel struct {
+		TaskID string `db:"task_id"`
+		Name   string `db:"name"`
+	}
+)
+
+func NewLabelRepo(db *sqlx.DB) *LabelRepo {
+	return &LabelRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *LabelRepo) WithTx(tx *sqlx.Tx) *LabelRepo {
+	return &LabelRepo{
+		db: tx,
+	}
+}
+
+func (r *LabelRepo) Create(ctx context.Context, l Label) (*Label, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO label(
+				name,
+				color,
+				created)
+		VALUES(:name,
+				:color,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				name,
+				color,
+				created`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare label create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &l, l)
+	if err != nil {
+		log.Printf("failed to create label: %v\n", err)
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *LabelRepo) GetAll(ctx context.Context) ([]Label, error) {
+	labels := []Label{}
+	err := r.db.SelectContext(
+		ctx, &labels, `
+		SELECT 	id,
+				name,
+				color,
+				created
+		FROM label
+		ORDER BY name`,
+	)
+	if err != nil {
+		log.Printf("failed to get all labels: %v\n", err)
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (r *LabelRepo) GetByID(ctx context.Context, uuid string) (*Label, error) {
+	var l Label
+	err := r.db.GetContext(
+		ctx, &l, `
+		SELECT 	id,
+				name,
+				color,
+				created
+		FROM label
+		WHERE id=$1`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to get label with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetByNames returns the labels with the given names. Unknown names are
+// skipped, so the result may be shorter than names.
+func (r *LabelRepo) GetByNames(ctx context.Context, names []string) ([]Label, error) {
+	labels := []Label{}
+	if len(names) == 0 {
+		return labels, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT 	id,
+				name,
+				color,
+				created
+		FROM label
+		WHERE name IN (?)
+		ORDER BY name`, names,
+	)
+	if err != nil {
+		log.Printf("failed to build label query: %v\n", err)
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &labels, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to get labels by names: %v\n", err)
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (r *LabelRepo) Update(ctx context.Context, l Label) (*Label, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx, buildLabelUpdateQuery(&l))
+	if err != nil {
+		log.Printf("failed to prepare label update query: %v\n", err)
+		return nil, err
+	}
+	if err = stmt.GetContext(ctx, &l, l); err != nil {
+		log.Printf("failed to update label with uuid %s: %v\n", l.ID, err)
+		return nil, err
+	}
+	return &l, nil
+}
+
+func buildLabelUpdateQuery(l *Label) string {
+	var queryBuilder strings.Builder
+
+	queryBuilder.WriteString(`UPDATE label SET `)
+	if l.Name != "" {
+		queryBuilder.WriteString(`name=:name, `)
+	}
+	if l.Color != "" {
+		queryBuilder.WriteString(`color=:color, `)
+	}
+	queryBuilder.WriteString(`id=:id `)
+	queryBuilder.WriteString(`WHERE id=:id `)
+	queryBuilder.WriteString(`RETURNING id, name, color, created`)
+	return queryBuilder.String()
+}
+
+func (r *LabelRepo) Delete(ctx context.Context, uuid string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM label WHERE id=$1;`, uuid)
+	if err != nil {
+		log.Printf("failed to delete label with id %s: %v\n", uuid, err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no label found with uuid %s", uuid)
+	}
+
+	return nil
+}
+
+// SetTaskLabels replaces the labels of the task with the given ones.
+func (r *LabelRepo) SetTaskLabels(ctx context.Context, taskID string, labels []Label) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_label WHERE task_id=$1;`, taskID)
+	if err != nil {
+		log.Printf("failed to remove labels of task %s: %v\n", taskID, err)
+		return err
+	}
+	for _, l := range labels {
+		_, err := r.db.ExecContext(ctx,
+			`INSERT INTO task_label(task_id, label_id) VALUES($1, $2);`, taskID, l.ID,
+		)
+		if err != nil {
+			log.Printf("failed to add label %s to task %s: %v\n", l.Name, taskID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// FillTaskLabels sets the Labels field of the tasks to the names of their
+// labels, sorted by name.
+func (r *LabelRepo) FillTaskLabels(ctx context.Context, tasks ...*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT 	tl.task_id,
+				l.name
+		FROM task_label tl
+		JOIN label l ON l.id=tl.label_id
+		WHERE tl.task_id IN (?)`, ids,
+	)
+	if err != nil {
+		log.Printf("failed to build task labels query: %v\n", err)
+		return err
+	}
+	var rows []taskLabel
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to get task labels: %v\n", err)
+		return err
+	}
+
+	names := make(map[string][]string, len(tasks))
+	for _, row := range rows {
+		names[row.TaskID] = append(names[row.TaskID], row.Name)
+	}
+	for _, t := range tasks {
+		t.Labels = names[t.ID]
+		if t.Labels == nil {
+			t.Labels = []string{}
+		}
+		sort.Strings(t.Labels)
+	}
+	return nil
+}
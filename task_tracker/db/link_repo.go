@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	LinkRepo struct {
+		db querier
+	}
+	// TaskLink means that the BlockerID task has to be completed before the
+	// BlockedID one.
+	TaskLink struct {
+		BlockerID string    `db:"blocker_id"`
+		BlockedID string    `db:"blocked_id"`
+		Created   time.Time `db:"created"`
+	}
+)
+
+func NewLinkRepo(db *sqlx.DB) *LinkRepo {
+	return &LinkRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *LinkRepo) WithTx(tx *sqlx.Tx) *LinkRepo {
+	return &LinkRepo{
+		db: tx,
+	}
+}
+
+// Lock serializes changes of the task dependency graph until the surrounding
+// transaction ends, so that concurrent changes can't make up a cycle. It must
+// be called within WithTx.
+func (r *LinkRepo) Lock(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_dependencies'));`)
+	if err != nil {
+		log.Printf("failed to lock task dependencies: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func (r *LinkRepo) Create(ctx context.Context, l TaskLink) (*TaskLink, error) {
+	err := r.db.GetContext(
+		ctx, &l, `
+		INSERT INTO task_link(
+				blocker_id,
+				blocked_id,
+				created)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET created=task_link.created
+		RETURNING
+				blocker_id,
+				blocked_id,
+				created`, l.BlockerID, l.BlockedID,
+	)
+	if err != nil {
+		log.Printf("failed to link task %s to %s: %v\n", l.BlockerID, l.BlockedID, err)
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *LinkRepo) Delete(ctx context.Context, blockerID, blockedID string) error {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM task_link WHERE blocker_id=$1 AND blocked_id=$2;`, blockerID, blockedID,
+	)
+	if err != nil {
+		log.Printf("failed to unlink task %s from %s: %v\n", blockerID, blockedID, err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// IsBlocking reports whether the blocker task blocks the blocked one directly
+// or through other tasks.
+func (r *LinkRepo) IsBlocking(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	var blocking bool
+	err := r.db.GetContext(
+		ctx, &blocking, `
+		WITH RECURSIVE blocked(id) AS (
+			SELECT blocked_id FROM task_link WHERE blocker_id=$1
+			UNION
+			SELECT l.blocked_id FROM task_link l JOIN blocked b ON l.blocker_id=b.id
+		)
+		SELECT EXISTS (SELECT 1 FROM blocked WHERE id=$2)`, blockerID, blockedID,
+	)
+	if err != nil {
+		log.Printf("failed to check whether task %s blocks %s: %v\n", blockerID, blockedID, err)
+		return false, err
+	}
+	return blocking, nil
+}
+
+// CountOpenBlockers returns the number of uncompleted tasks blocking the task.
+func (r *LinkRepo) CountOpenBlockers(ctx context.Context, taskID string) (int, error) {
+	var count int
+	err := r.db.GetContext(
+		ctx, &count, `
+		SELECT count(*)
+		FROM task_link l
+		JOIN task t ON t.id=l.blocker_id
+		WHERE l.blocked_id=$1 AND t.status<>'Completed' AND t.deleted_at IS NULL`, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to count blockers of task %s: %v\n", taskID, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetForTasks returns the links either end of which is one of the tasks.
+func (r *LinkRepo) GetForTasks(ctx context.Context, taskIDs []string) ([]TaskLink, error) {
+	links := []TaskLink{}
+	if len(taskIDs) == 0 {
+		return links, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT 	blocker_id,
+				blocked_id,
+				created
+		FROM task_link
+		WHERE blocker_id IN (?) OR blocked_id IN (?)
+		ORDER BY created, blocker_id, blocked_id`, taskIDs, taskIDs,
+	)
+	if err != nil {
+		log.Printf("failed to build task links query: %v\n", err)
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &links, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to get task links: %v\n", err)
+		return nil, err
+	}
+	return links, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	NotificationRepo struct {
+		db querier
+	}
+	NotificationPreference struct {
+		UserID  string    `db:"user_id"`
+		Mode    string    `db:"mode"`
+		Updated time.Time `db:"updated"`
+	}
+)
+
+func NewNotificationRepo(db *sqlx.DB) *NotificationRepo {
+	return &NotificationRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *NotificationRepo) WithTx(tx *sqlx.Tx) *NotificationRepo {
+	return &NotificationRepo{
+		db: tx,
+	}
+}
+
+// GetPreferences returns the preferences of the users who have set them.
+func (r *NotificationRepo) GetPreferences(ctx context.Context, userIDs []string) ([]NotificationPreference, error) {
+	prefs := []NotificationPreference{}
+	if len(userIDs) == 0 {
+		return prefs, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT 	user_id,
+				mode,
+				updated
+		FROM notification_preference
+		WHERE user_id IN (?)`, userIDs,
+	)
+	if err != nil {
+		log.Printf("failed to build notification preferences query: %v\n", err)
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &prefs, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to get notification preferences: %v\n", err)
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (r *NotificationRepo) SetPreference(ctx context.Context, p NotificationPreference) (*NotificationPreference, error) {
+	err := r.db.GetContext(
+		ctx, &p, `
+		INSERT INTO notification_preference(user_id, mode, updated)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE
+		SET mode=EXCLUDED.mode,
+			updated=EXCLUDED.updated
+		RETURNING
+				user_id,
+				mode,
+				updated`, p.UserID, p.Mode,
+	)
+	if err != nil {
+		log.Printf("failed to set notification preference of user %s: %v\n", p.UserID, err)
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	OutboxRepo struct {
+		db querier
+	}
+	OutboxMessage struct {
+		ID        int64      `db:"id"`
+		Topic     string     `db:"topic"`
+		Key       string     `db:"message_key"`
+		EventName string     `db:"event_name"`
+		Payload   []byte     `db:"payload"`
+		Attempts  int        `db:"attempts"`
+		LastError string     `db:"last_error"`
+		Created   time.Time  `db:"created"`
+		SentAt    *time.Time `db:"sent_at"`
+	}
+)
+
+func NewOutboxRepo(db *sqlx.DB) *OutboxRepo {
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *OutboxRepo) WithTx(tx *sqlx.Tx) *OutboxRepo {
+	return &OutboxRepo{
+		db: tx,
+	}
+}
+
+func (r *OutboxRepo) Create(ctx context.Context, m OutboxMessage) (*OutboxMessage, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO outbox(
+				topic,
+				message_key,
+				event_name,
+				payload,
+				created)
+		VALUES(:topic,
+				:message_key,
+				:event_name,
+				:payload,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				topic,
+				message_key,
+				event_name,
+				payload,
+				attempts,
+				last_error,
+				created,
+				sent_at`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare outbox create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &m, m)
+	if err != nil {
+		log.Printf("failed to create outbox message: %v\n", err)
+		return nil, err
+	}
+	return &m, nil
+}
+
+// LockPending returns up to limit unsent messages in insertion order. Rows are
+// locked until the surrounding transaction ends, and rows already locked by
+// another relay are skipped, so it must be called within WithTx.
+func (r *OutboxRepo) LockPending(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	var msgs []OutboxMessage
+	err := r.db.SelectContext(
+		ctx, &msgs, `
+		SELECT 	id,
+				topic,
+				message_key,
+				event_name,
+				payload,
+				attempts,
+				last_error,
+				created,
+				sent_at
+		FROM outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit,
+	)
+	if err != nil {
+		log.Printf("failed to get pending outbox messages: %v\n", err)
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (r *OutboxRepo) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET sent_at=CURRENT_TIMESTAMP,
+			attempts=attempts + 1,
+			last_error=''
+		WHERE id=$1`, id,
+	)
+	if err != nil {
+		log.Printf("failed to mark outbox message %d as sent: %v\n", id, err)
+		return err
+	}
+	return nil
+}
+
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, sendErr error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET attempts=attempts + 1,
+			last_error=$2
+		WHERE id=$1`, id, sendErr.Error(),
+	)
+	if err != nil {
+		log.Printf("failed to mark outbox message %d as failed: %v\n", id, err)
+		return err
+	}
+	return nil
+}
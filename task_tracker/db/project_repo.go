@@ -0,0 +1,261 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	ProjectRepo struct {
+		db querier
+	}
+	Project struct {
+		ID          string    `db:"id"`
+		Key         string    `db:"key"`
+		Name        string    `db:"name"`
+		Description string    `db:"description"`
+		Created     time.Time `db:"created"`
+	}
+	ProjectMember struct {
+		ProjectID string    `db:"project_id"`
+		UserID    string    `db:"user_id"`
+		Created   time.Time `db:"created"`
+	}
+)
+
+func NewProjectRepo(db *sqlx.DB) *ProjectRepo {
+	return &ProjectRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *ProjectRepo) WithTx(tx *sqlx.Tx) *ProjectRepo {
+	return &ProjectRepo{
+		db: tx,
+	}
+}
+
+func (r *ProjectRepo) Create(ctx context.Context, p Project) (*Project, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO project(
+				key,
+				name,
+				description,
+				created)
+		VALUES(:key,
+				:name,
+				:description,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				key,
+				name,
+				description,
+				created`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare project create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &p, p)
+	if err != nil {
+		log.Printf("failed to create project: %v\n", err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProjectRepo) GetAll(ctx context.Context) ([]Project, error) {
+	projects := []Project{}
+	err := r.db.SelectContext(
+		ctx, &projects, `
+		SELECT 	id,
+				key,
+				name,
+				description,
+				created
+		FROM project
+		ORDER BY key`,
+	)
+	if err != nil {
+		log.Printf("failed to get all projects: %v\n", err)
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *ProjectRepo) GetByID(ctx context.Context, uuid string) (*Project, error) {
+	var p Project
+	err := r.db.GetContext(
+		ctx, &p, `
+		SELECT 	id,
+				key,
+				name,
+				description,
+				created
+		FROM project
+		WHERE id=$1`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to get project with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProjectRepo) GetByKey(ctx context.Context, key string) (*Project, error) {
+	var p Project
+	err := r.db.GetContext(
+		ctx, &p, `
+		SELECT 	id,
+				key,
+				name,
+				description,
+				created
+		FROM project
+		WHERE key=$1`, key,
+	)
+	if err != nil {
+		log.Printf("failed to get project with key %s: %v\n", key, err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Update changes the name and the description of the project. The key is
+// never changed, since it's a part of the Jira IDs of the project tasks.
+func (r *ProjectRepo) Update(ctx context.Context, p Project) (*Project, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx, buildProjectUpdateQuery(&p))
+	if err != nil {
+		log.Printf("failed to prepare project update query: %v\n", err)
+		return nil, err
+	}
+	if err = stmt.GetContext(ctx, &p, p); err != nil {
+		log.Printf("failed to update project with uuid %s: %v\n", p.ID, err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func buildProjectUpdateQuery(p *Project) string {
+	var queryBuilder strings.Builder
+
+	queryBuilder.WriteString(`UPDATE project SET `)
+	if p.Name != "" {
+		queryBuilder.WriteString(`name=:name, `)
+	}
+	if p.Description != "" {
+		queryBuilder.WriteString(`description=:description, `)
+	}
+	queryBuilder.WriteString(`id=:id `)
+	queryBuilder.WriteString(`WHERE id=:id `)
+	queryBuilder.WriteString(`RETURNING id, key, name, description, created`)
+	return queryBuilder.String()
+}
+
+// NextJiraID returns the Jira ID for a new task of the project, such as
+// POP-123, and advances the project task counter. The counter row stays
+// locked until the surrounding transaction ends, so the IDs never repeat.
+func (r *ProjectRepo) NextJiraID(ctx context.Context, projectID string) (string, error) {
+	var jiraID string
+	err := r.db.GetContext(
+		ctx, &jiraID, `
+		UPDATE project SET next_task_number=next_task_number+1
+		WHERE id=$1
+		RETURNING key || '-' || (next_task_number-1)`, projectID,
+	)
+	if err != nil {
+		log.Printf("failed to get next jira id of project %s: %v\n", projectID, err)
+		return "", err
+	}
+	return jiraID, nil
+}
+
+// AddMembers adds the users to the project. Users already being members are
+// skipped.
+func (r *ProjectRepo) AddMembers(ctx context.Context, projectID string, userIDs ...string) error {
+	for _, userID := range userIDs {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO project_member(project_id, user_id, created)
+			VALUES($1, $2, CURRENT_TIMESTAMP)
+			ON CONFLICT (project_id, user_id) DO NOTHING`, projectID, userID,
+		)
+		if err != nil {
+			log.Printf("failed to add member %s to project %s: %v\n", userID, projectID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the project. sql.ErrNoRows is returned
+// if the user isn't a member of the project.
+func (r *ProjectRepo) RemoveMember(ctx context.Context, projectID, userID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM project_member WHERE project_id=$1 AND user_id=$2;`, projectID, userID)
+	if err != nil {
+		log.Printf("failed to remove member %s from project %s: %v\n", userID, projectID, err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *ProjectRepo) GetMembers(ctx context.Context, projectID string) ([]ProjectMember, error) {
+	return r.GetMembersOf(ctx, []string{projectID})
+}
+
+// GetMembersOf returns the members of all the given projects.
+func (r *ProjectRepo) GetMembersOf(ctx context.Context, projectIDs []string) ([]ProjectMember, error) {
+	members := []ProjectMember{}
+	if len(projectIDs) == 0 {
+		return members, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT 	project_id,
+				user_id,
+				created
+		FROM project_member
+		WHERE project_id IN (?)
+		ORDER BY project_id, created, user_id`, projectIDs,
+	)
+	if err != nil {
+		log.Printf("failed to build project member query: %v\n", err)
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &members, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to get project members: %v\n", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// IsMember reports whether the user is a member of the project.
+func (r *ProjectRepo) IsMember(ctx context.Context, projectID, userID string) (bool, error) {
+	var isMember bool
+	err := r.db.GetContext(
+		ctx, &isMember, `
+		SELECT EXISTS (
+			SELECT 1 FROM project_member WHERE project_id=$1 AND user_id=$2
+		)`, projectID, userID,
+	)
+	if err != nil {
+		log.Printf("failed to check membership of %s in project %s: %v\n", userID, projectID, err)
+		return false, err
+	}
+	return isMember, nil
+}
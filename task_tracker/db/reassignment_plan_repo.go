@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	ReassignmentPlanRepo struct {
+		db querier
+	}
+	ReassignmentPlan struct {
+		ID          string     `db:"id"`
+		ProjectID   string     `db:"project_id"`
+		Assignments []byte     `db:"assignments"`
+		Created     time.Time  `db:"created"`
+		AppliedAt   *time.Time `db:"applied_at"`
+		// Expired is only set by GetByIDForUpdate
+		Expired bool `db:"expired"`
+	}
+)
+
+func NewReassignmentPlanRepo(db *sqlx.DB) *ReassignmentPlanRepo {
+	return &ReassignmentPlanRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *ReassignmentPlanRepo) WithTx(tx *sqlx.Tx) *ReassignmentPlanRepo {
+	return &ReassignmentPlanRepo{
+		db: tx,
+	}
+}
+
+func (r *ReassignmentPlanRepo) Create(ctx context.Context, p ReassignmentPlan) (*ReassignmentPlan, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO reassignment_plan(
+				project_id,
+				assignments,
+				created)
+		VALUES(NULLIF(:project_id, '')::uuid,
+				:assignments,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				COALESCE(project_id::text, '') AS project_id,
+				assignments,
+				created,
+				applied_at`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare reassignment plan create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &p, p)
+	if err != nil {
+		log.Printf("failed to create reassignment plan: %v\n", err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetByIDForUpdate returns the plan locking it until the surrounding
+// transaction ends, so it must be called within WithTx. The plan is reported
+// as expired if it has been created more than ttl ago.
+func (r *ReassignmentPlanRepo) GetByIDForUpdate(ctx context.Context, uuid string, ttl time.Duration) (*ReassignmentPlan, error) {
+	var p ReassignmentPlan
+	err := r.db.GetContext(
+		ctx, &p, `
+		SELECT  id,
+				COALESCE(project_id::text, '') AS project_id,
+				assignments,
+				created,
+				applied_at,
+				created<CURRENT_TIMESTAMP - make_interval(secs => $2) AS expired
+		FROM reassignment_plan
+		WHERE id=$1
+		FOR UPDATE`, uuid, ttl.Seconds(),
+	)
+	if err != nil {
+		log.Printf("failed to get reassignment plan with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ReassignmentPlanRepo) MarkApplied(ctx context.Context, uuid string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reassignment_plan
+		SET applied_at=CURRENT_TIMESTAMP
+		WHERE id=$1`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to mark reassignment plan %s as applied: %v\n", uuid, err)
+		return err
+	}
+	return nil
+}
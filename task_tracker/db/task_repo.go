@@ -10,18 +10,99 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// taskColumns is the list of task columns selected & returned by the queries below.
+const taskColumns = `
+		id,
+		title,
+		jira_id,
+		description,
+		status,
+		assignee_id,
+		assign_fee,
+		complete_cost,
+		due_date,
+		COALESCE(parent_id::text, '') AS parent_id,
+		COALESCE(project_id::text, '') AS project_id,
+		deleted_at,
+		completed_at,
+		version,
+		priority,
+		estimate,
+		created`
+
+// The matches in search snippets are enclosed in these control characters
+// rather than markup, since the snippets are made of raw user text which has
+// to be escaped before any markup is added.
+const (
+	SnippetStartSel = "\x02"
+	SnippetStopSel  = "\x03"
+)
+
+// TaskSortColumns maps the fields tasks can be sorted by to the SQL type their
+// values are compared as when paginating.
+var TaskSortColumns = map[string]string{
+	"created":  "timestamp",
+	"title":    "varchar",
+	"priority": "varchar",
+	"estimate": "int",
+}
+
 type (
 	TaskRepo struct {
-		db *sqlx.DB
+		db querier
 	}
 	Task struct {
-		ID          string    `db:"id"`
-		Title       string    `db:"title"`
-		JiraID      string    `db:"jira_id"`
-		Description string    `db:"description"`
-		Status      string    `db:"status"`
-		AssigneeID  string    `db:"assignee_id"`
-		Created     time.Time `db:"created"`
+		ID           string     `db:"id"`
+		Title        string     `db:"title"`
+		JiraID       string     `db:"jira_id"`
+		Description  string     `db:"description"`
+		Status       string     `db:"status"`
+		AssigneeID   string     `db:"assignee_id"`
+		AssignFee    int        `db:"assign_fee"`
+		CompleteCost int        `db:"complete_cost"`
+		DueDate      *time.Time `db:"due_date"`
+		ParentID     string     `db:"parent_id"`
+		ProjectID    string     `db:"project_id"`
+		DeletedAt    *time.Time `db:"deleted_at"`
+		Version      int        `db:"version"`
+		Priority     string     `db:"priority"`
+		Estimate     int        `db:"estimate"`
+		Created      time.Time  `db:"created"`
+		// CompletedAt is the time the task was completed for the first time
+		CompletedAt *time.Time `db:"completed_at"`
+		// Labels are stored in task_label and filled in by LabelRepo.
+		Labels []string `db:"-"`
+	}
+	// TaskSearchHit is a task found by Search along with its rank and a snippet
+	// of its title & description. The snippet is raw user text with the matches
+	// enclosed in SnippetStartSel and SnippetStopSel.
+	TaskSearchHit struct {
+		Task
+		Rank    float64 `db:"rank"`
+		Snippet string  `db:"snippet"`
+	}
+	// TaskFilter narrows down and orders the tasks returned by List. Empty
+	// fields are ignored. Tasks are returned after the (CursorValue, CursorID)
+	// position in the requested order, if set.
+	TaskFilter struct {
+		Status      string     `db:"status"`
+		AssigneeID  string     `db:"assignee_id"`
+		ProjectID   string     `db:"project_id"`
+		JiraID      string     `db:"jira_id"`
+		Title       string     `db:"title"`
+		Label       string     `db:"label"`
+		Priority    string     `db:"priority"`
+		MinEstimate int        `db:"min_estimate"`
+		MaxEstimate int        `db:"max_estimate"`
+		CreatedFrom *time.Time `db:"created_from"`
+		CreatedTo   *time.Time `db:"created_to"`
+		// IncludeDeleted makes List return soft deleted tasks too
+		IncludeDeleted bool   `db:"-"`
+		SortBy         string `db:"-"`
+		Desc           bool   `db:"-"`
+		CursorValue    string `db:"cursor_value"`
+		CursorID       string `db:"cursor_id"`
+		Limit          int    `db:"limit"`
 	}
 )
 
@@ -31,6 +112,13 @@ func NewTaskRepo(db *sqlx.DB) *TaskRepo {
 	}
 }
 
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *TaskRepo) WithTx(tx *sqlx.Tx) *TaskRepo {
+	return &TaskRepo{
+		db: tx,
+	}
+}
+
 func (r *TaskRepo) Create(ctx context.Context, t Task) (*Task, error) {
 	stmt, err := r.db.PrepareNamedContext(ctx,
 		`
@@ -40,21 +128,28 @@ func (r *TaskRepo) Create(ctx context.Context, t Task) (*Task, error) {
 				description,
 				status,
 				assignee_id,
+				assign_fee,
+				complete_cost,
+				due_date,
+				parent_id,
+				project_id,
+				priority,
+				estimate,
 				created)
 		VALUES(:title,
 				:jira_id,
 				:description,
 				:status,
 				:assignee_id,
+				:assign_fee,
+				:complete_cost,
+				:due_date,
+				NULLIF(:parent_id, '')::uuid,
+				NULLIF(:project_id, '')::uuid,
+				:priority,
+				:estimate,
 				CURRENT_TIMESTAMP)
-		RETURNING
-				id,
-				title,
-				jira_id,
-				description,
-				status,
-				assignee_id,
-				created`,
+		RETURNING`+taskColumns,
 	)
 	if err != nil {
 		log.Printf("failed to prepare task create query: %v\n", err)
@@ -72,13 +167,7 @@ func (r *TaskRepo) GetByID(ctx context.Context, uuid string) (*Task, error) {
 	var t Task
 	err := r.db.GetContext(
 		ctx, &t, `
-		SELECT  id,
-				title,
-				jira_id,
-				description,
-				status,
-				assignee_id,
-				created
+		SELECT`+taskColumns+`
 		FROM task
 		WHERE id=$1`, uuid,
 	)
@@ -89,17 +178,52 @@ func (r *TaskRepo) GetByID(ctx context.Context, uuid string) (*Task, error) {
 	return &t, nil
 }
 
+// GetByIDForUpdate is like GetByID but locks the task row until the
+// surrounding transaction ends, so it must be called within WithTx.
+func (r *TaskRepo) GetByIDForUpdate(ctx context.Context, uuid string) (*Task, error) {
+	var t Task
+	err := r.db.GetContext(
+		ctx, &t, `
+		SELECT`+taskColumns+`
+		FROM task
+		WHERE id=$1
+		FOR UPDATE`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to get task with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetByIDs returns the tasks with the given ids. Deleted tasks are skipped.
+func (r *TaskRepo) GetByIDs(ctx context.Context, uuids []string) ([]Task, error) {
+	tasks := []Task{}
+	if len(uuids) == 0 {
+		return tasks, nil
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT`+taskColumns+`
+		FROM task
+		WHERE id IN (?) AND deleted_at IS NULL`, uuids,
+	)
+	if err != nil {
+		log.Printf("failed to build tasks query: %v\n", err)
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &tasks, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to get tasks by ids: %v\n", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
 func (r *TaskRepo) GetAll(ctx context.Context) ([]Task, error) {
 	var tasks []Task
 	err := r.db.SelectContext(
 		ctx, &tasks, `
-		SELECT 	id,
-				title,
-				jira_id,
-				description,
-				status,
-				assignee_id,
-				created
+		SELECT`+taskColumns+`
 		FROM task`,
 	)
 	if err != nil {
@@ -109,6 +233,237 @@ func (r *TaskRepo) GetAll(ctx context.Context) ([]Task, error) {
 	return tasks, nil
 }
 
+// GetOpen returns the tasks which are not completed yet. Only the tasks of
+// the project are returned unless projectID is empty.
+func (r *TaskRepo) GetOpen(ctx context.Context, projectID string) ([]Task, error) {
+	return r.getOpen(ctx, projectID, "")
+}
+
+// LockOpen is like GetOpen but locks the task rows until the surrounding
+// transaction ends, so it must be called within WithTx.
+func (r *TaskRepo) LockOpen(ctx context.Context, projectID string) ([]Task, error) {
+	return r.getOpen(ctx, projectID, "FOR UPDATE")
+}
+
+func (r *TaskRepo) getOpen(ctx context.Context, projectID, lockClause string) ([]Task, error) {
+	var tasks []Task
+	err := r.db.SelectContext(
+		ctx, &tasks, `
+		SELECT`+taskColumns+`
+		FROM task
+		WHERE status<>'Completed' AND deleted_at IS NULL
+		AND ($1::text='' OR project_id=NULLIF($1, '')::uuid)
+		ORDER BY id `+lockClause, projectID,
+	)
+	if err != nil {
+		log.Printf("failed to get open tasks: %v\n", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// LockNewlyOverdue returns up to limit uncompleted tasks whose due date is
+// before now and which haven't been reported as overdue yet. The tasks are
+// locked until the surrounding transaction ends, so it must be called within
+// WithTx. Tasks locked by another transaction are skipped.
+func (r *TaskRepo) LockNewlyOverdue(ctx context.Context, now time.Time, limit int) ([]Task, error) {
+	var tasks []Task
+	err := r.db.SelectContext(
+		ctx, &tasks, `
+		SELECT`+taskColumns+`
+		FROM task
+		WHERE status<>'Completed'
+			AND deleted_at IS NULL
+			AND overdue_notified_at IS NULL
+			AND due_date<$1
+		ORDER BY due_date, id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, now, limit,
+	)
+	if err != nil {
+		log.Printf("failed to get overdue tasks: %v\n", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// MarkOverdueNotified records that the task has been reported as overdue.
+func (r *TaskRepo) MarkOverdueNotified(ctx context.Context, uuid string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE task SET overdue_notified_at=CURRENT_TIMESTAMP WHERE id=$1;`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to mark task %s as overdue: %v\n", uuid, err)
+		return err
+	}
+	return nil
+}
+
+// IsAncestor reports whether the ancestor task is the parent of the task, or
+// the parent of its parent and so on.
+func (r *TaskRepo) IsAncestor(ctx context.Context, ancestorID, taskID string) (bool, error) {
+	var isAncestor bool
+	err := r.db.GetContext(
+		ctx, &isAncestor, `
+		WITH RECURSIVE ancestors(id) AS (
+			SELECT parent_id FROM task WHERE id=$2
+			UNION
+			SELECT t.parent_id FROM task t JOIN ancestors a ON t.id=a.id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id=$1)`, ancestorID, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to check whether task %s is ancestor of %s: %v\n", ancestorID, taskID, err)
+		return false, err
+	}
+	return isAncestor, nil
+}
+
+// CountOpenSubtasks returns the number of uncompleted direct subtasks of the task.
+func (r *TaskRepo) CountOpenSubtasks(ctx context.Context, uuid string) (int, error) {
+	var count int
+	err := r.db.GetContext(
+		ctx, &count, `
+		SELECT count(*)
+		FROM task
+		WHERE parent_id=$1 AND status<>'Completed' AND deleted_at IS NULL`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to count subtasks of task %s: %v\n", uuid, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetSubtree returns the task along with its subtasks, their subtasks and so on.
+// Deleted tasks are skipped along with their subtasks.
+func (r *TaskRepo) GetSubtree(ctx context.Context, uuid string) ([]Task, error) {
+	var tasks []Task
+	err := r.db.SelectContext(
+		ctx, &tasks, `
+		WITH RECURSIVE subtree AS (
+			SELECT * FROM task WHERE id=$1 AND deleted_at IS NULL
+			UNION
+			SELECT t.* FROM task t JOIN subtree s ON t.parent_id=s.id
+			WHERE t.deleted_at IS NULL
+		)
+		SELECT`+taskColumns+`
+		FROM subtree
+		ORDER BY created, id`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to get subtree of task %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Search returns up to limit tasks matching the web search style query, best
+// matches first. Only tasks of the assignee are searched, unless assigneeID is
+// empty. Deleted tasks are never returned.
+func (r *TaskRepo) Search(ctx context.Context, query, assigneeID string, limit int) ([]TaskSearchHit, error) {
+	hits := []TaskSearchHit{}
+	err := r.db.SelectContext(
+		ctx, &hits, `
+		SELECT`+taskColumns+`,
+				ts_rank(search_vector, q) AS rank,
+				ts_headline('english', title || ' ' || description, q, $4) AS snippet
+		FROM task, websearch_to_tsquery('english', $1) q
+		WHERE search_vector @@ q
+			AND deleted_at IS NULL
+			AND ($2::text='' OR assignee_id=NULLIF($2::text, '')::uuid)
+		ORDER BY rank DESC, created DESC, id
+		LIMIT $3`, query, assigneeID, limit,
+		`StartSel="`+SnippetStartSel+`", StopSel="`+SnippetStopSel+`", MaxFragments=2`,
+	)
+	if err != nil {
+		log.Printf("failed to search tasks: %v\n", err)
+		return nil, err
+	}
+	return hits, nil
+}
+
+func (r *TaskRepo) List(ctx context.Context, f TaskFilter) ([]Task, error) {
+	query, err := buildTaskListQuery(&f)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		log.Printf("failed to prepare task list query: %v\n", err)
+		return nil, err
+	}
+
+	tasks := []Task{}
+	if err = stmt.SelectContext(ctx, &tasks, f); err != nil {
+		log.Printf("failed to list tasks: %v\n", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func buildTaskListQuery(f *TaskFilter) (string, error) {
+	sortType, ok := TaskSortColumns[f.SortBy]
+	if !ok {
+		return "", fmt.Errorf("unknown sort field: %s", f.SortBy)
+	}
+	direction, cmp := "ASC", ">"
+	if f.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	var queryBuilder strings.Builder
+
+	queryBuilder.WriteString(`SELECT` + taskColumns + ` FROM task WHERE TRUE `)
+	if !f.IncludeDeleted {
+		queryBuilder.WriteString(`AND deleted_at IS NULL `)
+	}
+	if f.Status != "" {
+		queryBuilder.WriteString(`AND status=:status `)
+	}
+	if f.AssigneeID != "" {
+		queryBuilder.WriteString(`AND assignee_id=:assignee_id `)
+	}
+	if f.ProjectID != "" {
+		queryBuilder.WriteString(`AND project_id=CAST(:project_id AS uuid) `)
+	}
+	if f.JiraID != "" {
+		queryBuilder.WriteString(`AND jira_id=:jira_id `)
+	}
+	if f.Title != "" {
+		queryBuilder.WriteString(`AND title ILIKE '%' || :title || '%' `)
+	}
+	if f.Label != "" {
+		queryBuilder.WriteString(`AND EXISTS (
+			SELECT 1 FROM task_label tl JOIN label l ON l.id=tl.label_id
+			WHERE tl.task_id=task.id AND l.name=:label) `)
+	}
+	if f.Priority != "" {
+		queryBuilder.WriteString(`AND priority=:priority `)
+	}
+	if f.MinEstimate > 0 {
+		queryBuilder.WriteString(`AND estimate>=:min_estimate `)
+	}
+	if f.MaxEstimate > 0 {
+		queryBuilder.WriteString(`AND estimate<=:max_estimate `)
+	}
+	if f.CreatedFrom != nil {
+		queryBuilder.WriteString(`AND created>=:created_from `)
+	}
+	if f.CreatedTo != nil {
+		queryBuilder.WriteString(`AND created<:created_to `)
+	}
+	if f.CursorID != "" {
+		queryBuilder.WriteString(fmt.Sprintf(
+			`AND (%s, id) %s (CAST(:cursor_value AS %s), CAST(:cursor_id AS uuid)) `,
+			f.SortBy, cmp, sortType,
+		))
+	}
+	queryBuilder.WriteString(fmt.Sprintf(`ORDER BY %[1]s %[2]s, id %[2]s `, f.SortBy, direction))
+	queryBuilder.WriteString(`LIMIT :limit`)
+	return queryBuilder.String(), nil
+}
+
 func (r *TaskRepo) Update(ctx context.Context, t Task) (*Task, error) {
 	stmt, err := r.db.PrepareNamedContext(ctx, buildTaskUpdateQuery(&t))
 	if err != nil {
@@ -123,6 +478,10 @@ func (r *TaskRepo) Update(ctx context.Context, t Task) (*Task, error) {
 	return &t, nil
 }
 
+// overdueResetOnDueDateChange clears the overdue notification mark only if
+// the due date changes. The SET expressions see the row before the update.
+const overdueResetOnDueDateChange = `CASE WHEN due_date IS DISTINCT FROM CAST(:due_date AS timestamp) THEN NULL ELSE overdue_notified_at END`
+
 func buildTaskUpdateQuery(t *Task) string {
 	var queryBuilder strings.Builder
 
@@ -139,35 +498,83 @@ func buildTaskUpdateQuery(t *Task) string {
 	if t.Status != "" {
 		queryBuilder.WriteString(`status=:status, `)
 	}
+	if t.Status == "Completed" {
+		queryBuilder.WriteString(`completed_at=COALESCE(completed_at, CURRENT_TIMESTAMP), `)
+	}
 	if t.AssigneeID != "" {
 		queryBuilder.WriteString(`assignee_id=:assignee_id, `)
 	}
-	queryBuilder.WriteString(`id=:id `)
+	if t.DueDate != nil {
+		// a task with a new due date may become overdue once again, the
+		// same date is resent on reassignment and must not report it twice
+		queryBuilder.WriteString(`overdue_notified_at=` + overdueResetOnDueDateChange + `, `)
+		queryBuilder.WriteString(`due_date=:due_date, `)
+	}
+	if t.ParentID != "" {
+		queryBuilder.WriteString(`parent_id=CAST(:parent_id AS uuid), `)
+	}
+	if t.Priority != "" {
+		queryBuilder.WriteString(`priority=:priority, `)
+	}
+	if t.Estimate != 0 {
+		queryBuilder.WriteString(`estimate=:estimate, `)
+	}
+	queryBuilder.WriteString(`version=version+1 `)
 	queryBuilder.WriteString(`WHERE id=:id `)
-	queryBuilder.WriteString(`RETURNING 
-		id,
-		title,
-		description,
-		status,
-		assignee_id,
-		created`)
+	queryBuilder.WriteString(`RETURNING` + taskColumns)
 	return queryBuilder.String()
 }
 
-func (r *TaskRepo) Delete(ctx context.Context, uuid string) error {
-	res, err := r.db.ExecContext(ctx, `DELETE FROM task WHERE id=$1;`, uuid)
+// SoftDelete marks the task as deleted, so that it's hidden from the task
+// lists. sql.ErrNoRows is returned if there is no such task not deleted yet.
+func (r *TaskRepo) SoftDelete(ctx context.Context, uuid string) (*Task, error) {
+	var t Task
+	err := r.db.GetContext(
+		ctx, &t, `
+		UPDATE task
+		SET deleted_at=CURRENT_TIMESTAMP,
+			version=version+1
+		WHERE id=$1 AND deleted_at IS NULL
+		RETURNING`+taskColumns, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to delete task with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Restore brings back a soft deleted task. sql.ErrNoRows is returned if there
+// is no such deleted task.
+func (r *TaskRepo) Restore(ctx context.Context, uuid string) (*Task, error) {
+	var t Task
+	err := r.db.GetContext(
+		ctx, &t, `
+		UPDATE task
+		SET deleted_at=NULL,
+			version=version+1
+		WHERE id=$1 AND deleted_at IS NOT NULL
+		RETURNING`+taskColumns, uuid,
+	)
 	if err != nil {
-		log.Printf("failed to delete task with id %s: %v\n", uuid, err)
-		return err
+		log.Printf("failed to restore task with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Purge removes the tasks soft deleted before the given time for good and
+// returns the number of removed tasks.
+func (r *TaskRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM task WHERE deleted_at<$1;`, deletedBefore)
+	if err != nil {
+		log.Printf("failed to purge deleted tasks: %v\n", err)
+		return 0, err
 	}
 	affected, err := res.RowsAffected()
 	if err != nil {
 		log.Printf("failed to get affected rows: %v\n", err)
-		return err
-	}
-	if affected == 0 {
-		return fmt.Errorf("no task found with uuid %s", uuid)
+		return 0, err
 	}
-
-	return nil
+	return affected, nil
 }
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTaskUpdateQueryOverdueReset(t *testing.T) {
+	due := time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		task Task
+		// wantReset tells whether the overdue mark may be cleared at all
+		wantReset bool
+	}{
+		{
+			name: "reassignment of an overdue task",
+			// applyReassignment passes the whole task row along
+			task: Task{
+				ID:         "f2b9c3d4-0000-0000-0000-000000000000",
+				Title:      "Feed the parrots",
+				Status:     "Assigned",
+				AssigneeID: "e1a8b2c3-0000-0000-0000-000000000000",
+				DueDate:    &due,
+				Priority:   "P2",
+				Estimate:   3,
+			},
+			wantReset: true,
+		},
+		{
+			name: "update without a due date",
+			task: Task{
+				ID:    "f2b9c3d4-0000-0000-0000-000000000000",
+				Title: "Feed the parrots",
+			},
+			wantReset: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := buildTaskUpdateQuery(&tt.task)
+			if strings.Contains(query, "overdue_notified_at=NULL") {
+				t.Errorf("overdue mark is cleared regardless of the due date: %s", query)
+			}
+			resets := strings.Contains(query, "overdue_notified_at="+overdueResetOnDueDateChange)
+			if resets != tt.wantReset {
+				t.Errorf("overdue mark reset on due date change = %t, want %t: %s", resets, tt.wantReset, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// querier is implemented by both *sqlx.DB and *sqlx.Tx, so the same repo
+	// code can run either standalone or inside a transaction.
+	querier interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+		GetContext(ctx context.Context, dest any, query string, args ...any) error
+		SelectContext(ctx context.Context, dest any, query string, args ...any) error
+		PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+		Rebind(query string) string
+	}
+	TxManager struct {
+		db *sqlx.DB
+	}
+)
+
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{
+		db: db,
+	}
+}
+
+// WithTx runs fn inside a single transaction. The transaction is committed
+// if fn returns nil and rolled back otherwise.
+func (m *TxManager) WithTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("failed to begin transaction: %v\n", err)
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("failed to rollback transaction: %v\n", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
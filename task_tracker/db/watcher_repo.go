@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	WatcherRepo struct {
+		db querier
+	}
+	Watcher struct {
+		TaskID  string    `db:"task_id"`
+		UserID  string    `db:"user_id"`
+		Created time.Time `db:"created"`
+	}
+)
+
+func NewWatcherRepo(db *sqlx.DB) *WatcherRepo {
+	return &WatcherRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *WatcherRepo) WithTx(tx *sqlx.Tx) *WatcherRepo {
+	return &WatcherRepo{
+		db: tx,
+	}
+}
+
+// Add makes the users watch the task. Users already watching it are skipped.
+func (r *WatcherRepo) Add(ctx context.Context, taskID string, userIDs ...string) error {
+	for _, userID := range userIDs {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO task_watcher(task_id, user_id, created)
+			VALUES($1, $2, CURRENT_TIMESTAMP)
+			ON CONFLICT (task_id, user_id) DO NOTHING`, taskID, userID,
+		)
+		if err != nil {
+			log.Printf("failed to add watcher %s to task %s: %v\n", userID, taskID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *WatcherRepo) Remove(ctx context.Context, taskID, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_watcher WHERE task_id=$1 AND user_id=$2;`, taskID, userID)
+	if err != nil {
+		log.Printf("failed to remove watcher %s from task %s: %v\n", userID, taskID, err)
+		return err
+	}
+	return nil
+}
+
+func (r *WatcherRepo) GetByTask(ctx context.Context, taskID string) ([]Watcher, error) {
+	watchers := []Watcher{}
+	err := r.db.SelectContext(
+		ctx, &watchers, `
+		SELECT 	task_id,
+				user_id,
+				created
+		FROM task_watcher
+		WHERE task_id=$1
+		ORDER BY created, user_id`, taskID,
+	)
+	if err != nil {
+		log.Printf("failed to get watchers of task %s: %v\n", taskID, err)
+		return nil, err
+	}
+	return watchers, nil
+}
@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+const webhookDeliveryColumns = `
+		id,
+		subscription_id,
+		event_name,
+		payload,
+		status,
+		attempts,
+		next_attempt_at,
+		last_status_code,
+		last_error,
+		created,
+		delivered_at`
+
+type (
+	WebhookRepo struct {
+		db querier
+	}
+	WebhookSubscription struct {
+		ID  string `db:"id"`
+		URL string `db:"url"`
+		// EventTypes is a JSON array of event names
+		EventTypes []byte    `db:"event_types"`
+		Secret     string    `db:"secret"`
+		Active     bool      `db:"active"`
+		Created    time.Time `db:"created"`
+	}
+	WebhookDelivery struct {
+		ID             string     `db:"id"`
+		SubscriptionID string     `db:"subscription_id"`
+		EventName      string     `db:"event_name"`
+		Payload        []byte     `db:"payload"`
+		Status         string     `db:"status"`
+		Attempts       int        `db:"attempts"`
+		NextAttemptAt  time.Time  `db:"next_attempt_at"`
+		LastStatusCode *int       `db:"last_status_code"`
+		LastError      string     `db:"last_error"`
+		Created        time.Time  `db:"created"`
+		DeliveredAt    *time.Time `db:"delivered_at"`
+	}
+	// DueWebhookDelivery is a delivery along with where to deliver it to.
+	DueWebhookDelivery struct {
+		WebhookDelivery
+		URL    string `db:"url"`
+		Secret string `db:"secret"`
+	}
+	WebhookDeliveryAttempt struct {
+		ID         int64     `db:"id"`
+		DeliveryID string    `db:"delivery_id"`
+		StatusCode *int      `db:"status_code"`
+		Error      string    `db:"error"`
+		DurationMs int       `db:"duration_ms"`
+		Created    time.Time `db:"created"`
+	}
+)
+
+func NewWebhookRepo(db *sqlx.DB) *WebhookRepo {
+	return &WebhookRepo{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repo bound to the given transaction.
+func (r *WebhookRepo) WithTx(tx *sqlx.Tx) *WebhookRepo {
+	return &WebhookRepo{
+		db: tx,
+	}
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, s WebhookSubscription) (*WebhookSubscription, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx,
+		`
+		INSERT INTO webhook_subscription(
+				url,
+				event_types,
+				secret,
+				created)
+		VALUES(:url,
+				:event_types,
+				:secret,
+				CURRENT_TIMESTAMP)
+		RETURNING
+				id,
+				url,
+				event_types,
+				secret,
+				active,
+				created`,
+	)
+	if err != nil {
+		log.Printf("failed to prepare webhook subscription create query: %v\n", err)
+		return nil, err
+	}
+	err = stmt.GetContext(ctx, &s, s)
+	if err != nil {
+		log.Printf("failed to create webhook subscription: %v\n", err)
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *WebhookRepo) GetSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	subs := []WebhookSubscription{}
+	err := r.db.SelectContext(
+		ctx, &subs, `
+		SELECT 	id,
+				url,
+				event_types,
+				secret,
+				active,
+				created
+		FROM webhook_subscription
+		ORDER BY created, id`,
+	)
+	if err != nil {
+		log.Printf("failed to get webhook subscriptions: %v\n", err)
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *WebhookRepo) GetSubscriptionByID(ctx context.Context, uuid string) (*WebhookSubscription, error) {
+	var s WebhookSubscription
+	err := r.db.GetContext(
+		ctx, &s, `
+		SELECT 	id,
+				url,
+				event_types,
+				secret,
+				active,
+				created
+		FROM webhook_subscription
+		WHERE id=$1`, uuid,
+	)
+	if err != nil {
+		log.Printf("failed to get webhook subscription with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, uuid string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscription WHERE id=$1;`, uuid)
+	if err != nil {
+		log.Printf("failed to delete webhook subscription with id %s: %v\n", uuid, err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("failed to get affected rows: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CreateDeliveries makes a pending delivery of the event for every active
+// subscription to it.
+func (r *WebhookRepo) CreateDeliveries(ctx context.Context, eventName string, payload []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_delivery(
+				subscription_id,
+				event_name,
+				payload,
+				status,
+				next_attempt_at,
+				created)
+		SELECT 	id,
+				$1,
+				$2,
+				$3,
+				CURRENT_TIMESTAMP,
+				CURRENT_TIMESTAMP
+		FROM webhook_subscription
+		WHERE active AND event_types @> jsonb_build_array($1::text)`,
+		eventName, payload, WebhookDeliveryPending,
+	)
+	if err != nil {
+		log.Printf("failed to create webhook deliveries of %s: %v\n", eventName, err)
+		return err
+	}
+	return nil
+}
+
+// CreateDelivery makes a pending delivery of the event to the subscription
+// regardless of the event types it's subscribed to. The delivery isn't due
+// until lease passes, so a caller attempting it right away doesn't race with
+// the dispatchers.
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, d WebhookDelivery, lease time.Duration) (*WebhookDelivery, error) {
+	err := r.db.GetContext(
+		ctx, &d, `
+		INSERT INTO webhook_delivery(
+				subscription_id,
+				event_name,
+				payload,
+				status,
+				next_attempt_at,
+				created)
+		VALUES($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5), CURRENT_TIMESTAMP)
+		RETURNING`+webhookDeliveryColumns,
+		d.SubscriptionID, d.EventName, d.Payload, WebhookDeliveryPending, lease.Seconds(),
+	)
+	if err != nil {
+		log.Printf("failed to create webhook delivery of %s: %v\n", d.EventName, err)
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ClaimDue returns up to limit pending deliveries which are due and postpones
+// their next attempt by lease, so that other dispatchers don't pick them up
+// while they are being delivered.
+func (r *WebhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]DueWebhookDelivery, error) {
+	deliveries := []DueWebhookDelivery{}
+	err := r.db.SelectContext(
+		ctx, &deliveries, `
+		WITH due AS (
+			SELECT id
+			FROM webhook_delivery
+			WHERE status=$1 AND next_attempt_at<=CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at, created
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_delivery d
+		SET next_attempt_at=CURRENT_TIMESTAMP + make_interval(secs => $3)
+		FROM due, webhook_subscription s
+		WHERE d.id=due.id AND s.id=d.subscription_id
+		RETURNING
+				d.id,
+				d.subscription_id,
+				d.event_name,
+				d.payload,
+				d.status,
+				d.attempts,
+				d.next_attempt_at,
+				d.last_status_code,
+				d.last_error,
+				d.created,
+				d.delivered_at,
+				s.url,
+				s.secret`, WebhookDeliveryPending, limit, lease.Seconds(),
+	)
+	if err != nil {
+		log.Printf("failed to claim due webhook deliveries: %v\n", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt stores the attempt and updates the delivery status. Pending
+// deliveries are attempted again after retryIn.
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, a WebhookDeliveryAttempt, status string, retryIn time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_delivery_attempt(
+				delivery_id,
+				status_code,
+				error,
+				duration_ms,
+				created)
+		VALUES($1, $2, $3, $4, CURRENT_TIMESTAMP)`,
+		a.DeliveryID, a.StatusCode, a.Error, a.DurationMs,
+	)
+	if err != nil {
+		log.Printf("failed to record attempt of webhook delivery %s: %v\n", a.DeliveryID, err)
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE webhook_delivery
+		SET status=$2,
+			attempts=attempts + 1,
+			last_status_code=$3,
+			last_error=$4,
+			next_attempt_at=CURRENT_TIMESTAMP + make_interval(secs => $5),
+			delivered_at=CASE WHEN $2=$6 THEN CURRENT_TIMESTAMP END
+		WHERE id=$1`,
+		a.DeliveryID, status, a.StatusCode, a.Error, retryIn.Seconds(), WebhookDeliveryDelivered,
+	)
+	if err != nil {
+		log.Printf("failed to update webhook delivery %s: %v\n", a.DeliveryID, err)
+		return err
+	}
+	return nil
+}
+
+// GetDeliveries returns up to limit latest deliveries to the subscription.
+func (r *WebhookRepo) GetDeliveries(ctx context.Context, subscriptionID string, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := r.db.SelectContext(
+		ctx, &deliveries, `
+		SELECT`+webhookDeliveryColumns+`
+		FROM webhook_delivery
+		WHERE subscription_id=$1
+		ORDER BY created DESC, id
+		LIMIT $2`, subscriptionID, limit,
+	)
+	if err != nil {
+		log.Printf("failed to get deliveries of webhook subscription %s: %v\n", subscriptionID, err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) GetDeliveryByID(ctx context.Context, subscriptionID, uuid string) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := r.db.GetContext(
+		ctx, &d, `
+		SELECT`+webhookDeliveryColumns+`
+		FROM webhook_delivery
+		WHERE id=$1 AND subscription_id=$2`, uuid, subscriptionID,
+	)
+	if err != nil {
+		log.Printf("failed to get webhook delivery with uuid %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *WebhookRepo) GetAttempts(ctx context.Context, deliveryID string) ([]WebhookDeliveryAttempt, error) {
+	attempts := []WebhookDeliveryAttempt{}
+	err := r.db.SelectContext(
+		ctx, &attempts, `
+		SELECT 	id,
+				delivery_id,
+				status_code,
+				error,
+				duration_ms,
+				created
+		FROM webhook_delivery_attempt
+		WHERE delivery_id=$1
+		ORDER BY created, id`, deliveryID,
+	)
+	if err != nil {
+		log.Printf("failed to get attempts of webhook delivery %s: %v\n", deliveryID, err)
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// Replay makes the delivery pending again with a fresh attempt budget.
+func (r *WebhookRepo) Replay(ctx context.Context, subscriptionID, uuid string) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := r.db.GetContext(
+		ctx, &d, `
+		UPDATE webhook_delivery
+		SET status=$3,
+			attempts=0,
+			next_attempt_at=CURRENT_TIMESTAMP,
+			delivered_at=NULL
+		WHERE id=$1 AND subscription_id=$2
+		RETURNING`+webhookDeliveryColumns, uuid, subscriptionID, WebhookDeliveryPending,
+	)
+	if err != nil {
+		log.Printf("failed to replay webhook delivery %s: %v\n", uuid, err)
+		return nil, err
+	}
+	return &d, nil
+}
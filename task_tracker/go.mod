@@ -37,3 +37,5 @@ require (
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.28.0 // indirect
 )
+
+replace github.com/ko3luhbka/popug_schema_registry => ../popug_schema_registry
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+ADD COLUMN assign_fee int NOT NULL DEFAULT 0,
+ADD COLUMN complete_cost int NOT NULL DEFAULT 0;
+
+-- price tasks created before prices were stored with the same formula as new ones
+UPDATE task
+SET assign_fee = floor(random() * 10 + 10),
+    complete_cost = floor(random() * 20 + 20);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE task
+DROP COLUMN assign_fee,
+DROP COLUMN complete_cost;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX task_status_idx ON task (status);
+CREATE INDEX task_assignee_id_idx ON task (assignee_id);
+CREATE INDEX task_jira_id_idx ON task (jira_id);
+CREATE INDEX task_created_id_idx ON task (created, id);
+CREATE INDEX task_title_id_idx ON task (title, id);
+CREATE INDEX task_title_trgm_idx ON task USING gin (title gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX task_title_trgm_idx;
+DROP INDEX task_title_id_idx;
+DROP INDEX task_created_id_idx;
+DROP INDEX task_jira_id_idx;
+DROP INDEX task_assignee_id_idx;
+DROP INDEX task_status_idx;
+-- +goose StatementEnd
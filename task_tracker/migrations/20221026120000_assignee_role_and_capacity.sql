@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE assignee
+ADD COLUMN role varchar NOT NULL DEFAULT '',
+ADD COLUMN capacity int NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE assignee
+DROP COLUMN role,
+DROP COLUMN capacity;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reassignment_plan (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    assignments jsonb NOT NULL,
+    created timestamp NOT NULL,
+    applied_at timestamp
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE reassignment_plan;
+-- +goose StatementEnd
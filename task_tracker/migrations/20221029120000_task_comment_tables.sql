@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE task_comment (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    author_id uuid NOT NULL,
+    body text NOT NULL,
+    created timestamp NOT NULL,
+    updated timestamp
+);
+
+CREATE INDEX task_comment_task_id_idx ON task_comment (task_id, created);
+
+CREATE TABLE task_comment_edit (
+    id bigserial PRIMARY KEY,
+    comment_id uuid NOT NULL REFERENCES task_comment (id) ON DELETE CASCADE,
+    body text NOT NULL,
+    editor_id uuid NOT NULL,
+    edited timestamp NOT NULL
+);
+
+CREATE INDEX task_comment_edit_comment_id_idx ON task_comment_edit (comment_id, edited);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_comment_edit;
+DROP TABLE task_comment;
+-- +goose StatementEnd
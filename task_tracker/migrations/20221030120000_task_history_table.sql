@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE task_history (
+    id bigserial PRIMARY KEY,
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    field varchar(32) NOT NULL,
+    old_value text NOT NULL,
+    new_value text NOT NULL,
+    actor_id uuid NOT NULL,
+    changed timestamp NOT NULL
+);
+
+CREATE INDEX task_history_task_id_idx ON task_history (task_id, changed);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_history;
+-- +goose StatementEnd
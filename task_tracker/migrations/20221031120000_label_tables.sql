@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE label (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    name varchar(64) NOT NULL UNIQUE,
+    color varchar(7) NOT NULL DEFAULT '',
+    created timestamp NOT NULL
+);
+
+CREATE TABLE task_label (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    label_id uuid NOT NULL REFERENCES label (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX task_label_label_id_idx ON task_label (label_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_label;
+DROP TABLE label;
+-- +goose StatementEnd
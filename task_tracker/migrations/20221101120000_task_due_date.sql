@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+ADD COLUMN due_date timestamp,
+ADD COLUMN overdue_notified_at timestamp;
+
+CREATE INDEX task_overdue_idx ON task (due_date)
+WHERE status <> 'Completed' AND overdue_notified_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX task_overdue_idx;
+
+ALTER TABLE task
+DROP COLUMN due_date,
+DROP COLUMN overdue_notified_at;
+-- +goose StatementEnd
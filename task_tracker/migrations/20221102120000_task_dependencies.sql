@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+ADD COLUMN parent_id uuid REFERENCES task (id) ON DELETE SET NULL;
+
+CREATE INDEX task_parent_id_idx ON task (parent_id);
+
+-- blocker_id task has to be completed before blocked_id one
+CREATE TABLE task_link (
+    blocker_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    blocked_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    created timestamp NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX task_link_blocked_id_idx ON task_link (blocked_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_link;
+
+ALTER TABLE task
+DROP COLUMN parent_id;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+ADD COLUMN deleted_at timestamp;
+
+CREATE INDEX task_deleted_at_idx ON task (deleted_at)
+WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX task_deleted_at_idx;
+
+ALTER TABLE task
+DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE idempotency_key (
+    key varchar(255) NOT NULL,
+    endpoint varchar(255) NOT NULL,
+    request_hash char(64) NOT NULL,
+    -- response fields stay NULL until the first request is handled
+    status_code int,
+    content_type varchar(255),
+    response bytea,
+    created timestamp NOT NULL,
+    PRIMARY KEY (key, endpoint)
+);
+
+CREATE INDEX idempotency_key_created_idx ON idempotency_key (created);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE idempotency_key;
+-- +goose StatementEnd
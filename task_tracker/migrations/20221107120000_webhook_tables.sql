@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webhook_subscription (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    url varchar(2048) NOT NULL,
+    event_types jsonb NOT NULL,
+    secret varchar(255) NOT NULL,
+    active boolean NOT NULL DEFAULT TRUE,
+    created timestamp NOT NULL
+);
+
+CREATE TABLE webhook_delivery (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    subscription_id uuid NOT NULL REFERENCES webhook_subscription (id) ON DELETE CASCADE,
+    event_name varchar(255) NOT NULL,
+    payload bytea NOT NULL,
+    -- pending, delivered or failed
+    status varchar(16) NOT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL,
+    last_status_code int,
+    last_error text NOT NULL DEFAULT '',
+    created timestamp NOT NULL,
+    delivered_at timestamp
+);
+
+CREATE INDEX webhook_delivery_pending_idx ON webhook_delivery (next_attempt_at)
+WHERE status = 'pending';
+
+CREATE INDEX webhook_delivery_subscription_id_idx ON webhook_delivery (subscription_id, created);
+
+CREATE TABLE webhook_delivery_attempt (
+    id bigserial PRIMARY KEY,
+    delivery_id uuid NOT NULL REFERENCES webhook_delivery (id) ON DELETE CASCADE,
+    status_code int,
+    error text NOT NULL DEFAULT '',
+    duration_ms int NOT NULL,
+    created timestamp NOT NULL
+);
+
+CREATE INDEX webhook_delivery_attempt_delivery_id_idx ON webhook_delivery_attempt (delivery_id, created);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_delivery_attempt;
+DROP TABLE webhook_delivery;
+DROP TABLE webhook_subscription;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE task_feed (
+    id bigserial PRIMARY KEY,
+    kind varchar(32) NOT NULL,
+    task_id uuid NOT NULL,
+    assignee_id uuid NOT NULL,
+    previous_assignee_id uuid,
+    payload jsonb NOT NULL,
+    created timestamp NOT NULL
+);
+
+CREATE INDEX task_feed_created_idx ON task_feed (created);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_feed;
+-- +goose StatementEnd
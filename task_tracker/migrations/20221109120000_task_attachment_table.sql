@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE task_attachment (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    filename varchar(255) NOT NULL,
+    content_type varchar(255) NOT NULL,
+    size bigint NOT NULL,
+    -- hex encoded SHA-256 of the content
+    checksum char(64) NOT NULL,
+    storage_key varchar(512) NOT NULL UNIQUE,
+    uploader_id uuid NOT NULL,
+    created timestamp NOT NULL
+);
+
+CREATE INDEX task_attachment_task_id_idx ON task_attachment (task_id, created);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE task_attachment;
+-- +goose StatementEnd
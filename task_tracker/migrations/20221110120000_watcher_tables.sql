@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE task_watcher (
+    task_id uuid NOT NULL REFERENCES task (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    created timestamp NOT NULL,
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE TABLE notification_preference (
+    user_id uuid PRIMARY KEY,
+    -- email, digest or off
+    mode varchar(16) NOT NULL,
+    updated timestamp NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notification_preference;
+DROP TABLE task_watcher;
+-- +goose StatementEnd
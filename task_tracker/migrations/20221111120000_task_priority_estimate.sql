@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task
+    ADD COLUMN priority varchar(2) NOT NULL DEFAULT 'P2' CHECK (priority IN ('P0', 'P1', 'P2', 'P3')),
+    -- story points, 0 stands for a task which is not estimated
+    ADD COLUMN estimate int NOT NULL DEFAULT 0 CHECK (estimate >= 0);
+
+CREATE INDEX task_priority_idx ON task (priority);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX task_priority_idx;
+
+ALTER TABLE task
+    DROP COLUMN estimate,
+    DROP COLUMN priority;
+-- +goose StatementEnd
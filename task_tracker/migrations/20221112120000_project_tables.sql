@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE project (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid (),
+    key varchar(10) NOT NULL UNIQUE,
+    name varchar(255) NOT NULL,
+    description text NOT NULL DEFAULT '',
+    -- number the Jira ID of the next task of the project gets
+    next_task_number int NOT NULL DEFAULT 1,
+    created timestamp NOT NULL
+);
+
+CREATE TABLE project_member (
+    project_id uuid NOT NULL REFERENCES project (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    created timestamp NOT NULL,
+    PRIMARY KEY (project_id, user_id)
+);
+
+ALTER TABLE task ADD COLUMN project_id uuid REFERENCES project (id);
+
+CREATE INDEX task_project_id_idx ON task (project_id);
+
+ALTER TABLE task_feed ADD COLUMN project_id uuid;
+
+-- plans of a project reassignment only cover the open tasks of the project
+ALTER TABLE reassignment_plan ADD COLUMN project_id uuid REFERENCES project (id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE reassignment_plan DROP COLUMN project_id;
+
+ALTER TABLE task_feed DROP COLUMN project_id;
+
+DROP INDEX task_project_id_idx;
+
+ALTER TABLE task DROP COLUMN project_id;
+
+DROP TABLE project_member;
+DROP TABLE project;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- time the task was completed for the first time, the reward is only paid then
+ALTER TABLE task ADD COLUMN completed_at timestamp;
+
+UPDATE task SET completed_at=CURRENT_TIMESTAMP WHERE status='Completed';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE task DROP COLUMN completed_at;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- feed entries become visible when their transaction commits, which happens
+-- out of the id order, so clients follow the feed in the (txid, id) order
+-- and only up to the oldest transaction still in progress
+ALTER TABLE task_feed ADD COLUMN txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX task_feed_txid_id_idx ON task_feed (txid, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX task_feed_txid_id_idx;
+
+ALTER TABLE task_feed DROP COLUMN txid;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the key of a first request which never finished may be reserved again once
+-- the lease is over
+ALTER TABLE idempotency_key ADD COLUMN locked_until timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_key DROP COLUMN locked_until;
+-- +goose StatementEnd
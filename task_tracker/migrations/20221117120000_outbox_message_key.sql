@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- messages of the same task share a key, so they land on one partition and
+-- are consumed in order
+ALTER TABLE outbox ADD COLUMN message_key text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox DROP COLUMN message_key;
+-- +goose StatementEnd
@@ -1,6 +1,8 @@
 package mq
 
 import (
+	"time"
+
 	"github.com/ko3luhbka/task_tracker/rest/model"
 	"github.com/segmentio/kafka-go"
 )
@@ -9,14 +11,29 @@ const (
 	kafkaHost = "localhost:29092"
 	GroupID   = "usersConsumer"
 
+	// OutboxIDHeader carries the id of the outbox message a Kafka message was
+	// relayed from, so consumers can skip messages relayed more than once
+	OutboxIDHeader = "outboxId"
+
 	UsersCUDTopic    = "usersStreaming"
 	UserCreatedEvent = "userCreated"
 	UserUpdatedEvent = "userUpdated"
 	UserDeletedEvent = "userDeleted"
 
-	TasksTopic           = "tasks"
-	TaskAssignedEvent    = "taskAssigned"
-	TaskCompleted        = "taskCompleted"
+	TasksTopic             = "tasks"
+	TaskAssignedEvent      = "taskAssigned"
+	TaskCompleted          = "taskCompleted"
+	TaskStatusChangedEvent = "taskStatusChanged"
+	TaskUpdatedEvent       = "taskUpdated"
+	TasksReassignedEvent   = "tasksReassigned"
+	TaskCommentedEvent     = "taskCommented"
+	TaskOverdueEvent       = "taskOverdue"
+	TaskDeletedEvent       = "taskDeleted"
+	TaskRestoredEvent      = "taskRestored"
+
+	NotificationsTopic      = "notifications"
+	TaskAssignedToYouEvent  = "taskAssignedToYou"
+	WatchedTaskChangedEvent = "watchedTaskChanged"
 )
 
 type (
@@ -40,6 +57,21 @@ type (
 		Version int `json:"version"`
 		Data model.TaskInfo `json:"data"`
 	}
+	CommentEvent struct {
+		Name    string        `json:"name"`
+		Version int           `json:"version"`
+		Data    model.Comment `json:"data"`
+	}
+	NotificationEvent struct {
+		Name    string             `json:"name"`
+		Version int                `json:"version"`
+		Data    model.Notification `json:"data"`
+	}
+	ReassignmentEvent struct {
+		Name    string                 `json:"name"`
+		Version int                    `json:"version"`
+		Data    model.ReassignmentInfo `json:"data"`
+	}
 )
 
 func NewMQClient(cfg *Config) *Client {
@@ -59,9 +91,13 @@ func NewMQClient(cfg *Config) *Client {
 		w := &kafka.Writer{
 			Addr:                   kafka.TCP(kafkaHost),
 			Topic:                  cfg.WriteTopic,
-			Balancer:               &kafka.LeastBytes{},
+			// messages with the same key, such as the events of a task, go to
+			// the same partition and keep their order
+			Balancer:               &kafka.Hash{},
 			AllowAutoTopicCreation: true,
 			RequiredAcks:           1,
+			// the outbox relay writes synchronously, don't wait for more messages
+			BatchTimeout: 10 * time.Millisecond,
 		}
 		client.Writer = w
 	}
@@ -1,18 +1,37 @@
 package rest
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/ko3luhbka/task_tracker/rest/model"
+	"github.com/ko3luhbka/task_tracker/service"
 )
 
+// nextCursorHeader tells the clients listing the tasks without pagination
+// parameters where the next page starts.
+const nextCursorHeader = "X-Next-Cursor"
+
 func (s Server) ping(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON("pong")
 }
 
 func (s Server) createTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
 	var t model.Task
 	if err := c.BodyParser(&t); err != nil {
 		log.Printf("failed to parse body: %v\n", err)
@@ -24,7 +43,14 @@ func (s Server) createTask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
 	}
 
-	created, err := s.Svc.CreateTask(c.Context(), t)
+	created, err := s.Svc.CreateTask(c.Context(), t, claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrUnknownLabel) || errors.Is(err, service.ErrParentNotFound) ||
+		errors.Is(err, service.ErrProjectNotFound) || errors.Is(err, service.ErrNoProjectWorkers) {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
@@ -32,28 +58,208 @@ func (s Server) createTask(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(created)
 }
 
+// getAllTasks returns a page of tasks if limit or cursor is given. Otherwise
+// the first page of the default size is returned as a bare array, the way the
+// tasks were listed before the pagination was introduced, with the cursor of
+// the next page in the X-Next-Cursor header.
 func (s Server) getAllTasks(c *fiber.Ctx) error {
-	tasks, err := s.Svc.GetAllTasks(c.Context())
+	var q model.TaskListQuery
+	if err := c.QueryParser(&q); err != nil {
+		log.Printf("failed to parse query: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	f, err := q.ToFilter()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	page, err := s.Svc.ListTasks(c.Context(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if !q.IsPaginated() {
+		if page.Next != "" {
+			c.Set(nextCursorHeader, page.Next)
+		}
+		return c.Status(fiber.StatusOK).JSON(page.Tasks)
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func (s Server) getMyTasks(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	var q model.TaskListQuery
+	if err := c.QueryParser(&q); err != nil {
+		log.Printf("failed to parse query: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	f, err := q.ToFilter()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	f.AssigneeID = claims.UUID
+	f.IncludeDeleted = false
+
+	page, err := s.Svc.ListTasks(c.Context(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func (s Server) searchTasks(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	var q model.TaskSearchQuery
+	if err := c.QueryParser(&q); err != nil {
+		log.Printf("failed to parse query: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := q.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	hits, err := s.Svc.SearchTasks(c.Context(), q, claims.UUID, claims.Role == adminRole)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(hits)
+}
+
+func (s Server) importTasks(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	dryRun, err := strconv.ParseBool(c.Query("dry_run", "false"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid dry_run value")
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = model.TaskFormatJSON
+		if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+			format = model.TaskFormatCSV
+		}
+	}
+
+	rows, err := model.ParseTaskImport(bytes.NewReader(c.Body()), format)
+	if err != nil {
+		log.Printf("failed to parse tasks to import: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	report, err := s.Svc.ImportTasks(c.Context(), rows, dryRun, claims.UUID, claims.Role == adminRole)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
-	return c.Status(fiber.StatusOK).JSON(tasks)
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+func (s Server) exportTasks(c *fiber.Ctx) error {
+	format := c.Query("format", model.TaskFormatJSON)
+	if err := model.ValidateTaskFormat(format); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	var q model.TaskListQuery
+	if err := c.QueryParser(&q); err != nil {
+		log.Printf("failed to parse query: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	f, err := q.ToExportFilter()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if format == model.TaskFormatCSV {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="tasks.`+format+`"`)
+
+	// the body is written after the handler returns, so the request context
+	// can't be used by then
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		var write func(tasks []model.Task) error
+		if format == model.TaskFormatCSV {
+			cw := csv.NewWriter(w)
+			if err := cw.Write(model.TaskCSVHeader()); err != nil {
+				log.Printf("failed to export tasks: %v\n", err)
+				return
+			}
+			write = func(tasks []model.Task) error {
+				for _, t := range tasks {
+					if err := cw.Write(t.CSVRecord()); err != nil {
+						return err
+					}
+				}
+				cw.Flush()
+				return cw.Error()
+			}
+		} else {
+			enc := json.NewEncoder(w)
+			write = func(tasks []model.Task) error {
+				for _, t := range tasks {
+					if err := enc.Encode(t); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+
+		err := s.Svc.ExportTasks(context.Background(), f, func(tasks []model.Task) error {
+			if err := write(tasks); err != nil {
+				return err
+			}
+			return w.Flush()
+		})
+		if err != nil {
+			log.Printf("failed to export tasks: %v\n", err)
+		}
+	})
+	return nil
 }
 
 func (s Server) getTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
 	id, err := s.parseID(c)
 	if err != nil {
 		log.Println(err)
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
-	t, err := s.Svc.GetTaskByID(c.Context(), id)
+	t, err := s.Svc.GetTaskByID(c.Context(), id, claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
+	c.Set(fiber.HeaderETag, t.ETag())
 	return c.Status(fiber.StatusOK).JSON(t)
 }
 
 func (s Server) updateTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
 	var t model.Task
 	uuid, err := s.parseID(c)
 	if err != nil {
@@ -68,40 +274,978 @@ func (s Server) updateTask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
 	}
 	t.ID = uuid
+	// the update is applied only to the versions the client has seen, if it
+	// tells which ones in If-Match, the version in the body is ignored
+	t.Version = 0
+	var versions []int
+	if ifMatch := c.Get(fiber.HeaderIfMatch); ifMatch != "" {
+		versions, err = model.ParseIfMatch(ifMatch)
+		if errors.Is(err, model.ErrWeakETag) {
+			return c.Status(fiber.StatusPreconditionFailed).SendString(err.Error())
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+	}
 
-	updated, err := s.Svc.UpdateTask(c.Context(), t)
+	updated, err := s.Svc.UpdateTask(c.Context(), t, versions, claims.UUID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrVersionMismatch) {
+		return c.Status(fiber.StatusPreconditionFailed).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrIllegalTransition) ||
+		errors.Is(err, service.ErrDependencyCycle) ||
+		errors.Is(err, service.ErrOpenDependencies) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrUnknownLabel) || errors.Is(err, service.ErrParentNotFound) ||
+		errors.Is(err, service.ErrIllegalProjectChange) {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, updated.ETag())
 	return c.Status(fiber.StatusOK).JSON(updated)
 }
 
 func (s Server) deleteTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
 	id, err := s.parseID(c)
 	if err != nil {
 		log.Println(err)
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
-	if err := s.Svc.DeleteTask(c.Context(), id); err != nil {
+
+	err = s.Svc.DeleteTask(c.Context(), id, claims.UUID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
 
+func (s Server) restoreTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	id, err := s.parseID(c)
+	if err != nil {
+		log.Println(err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	restored, err := s.Svc.RestoreTask(c.Context(), id, claims.UUID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(restored)
+}
+
 func (s Server) reassignTasks(c *fiber.Ctx) error {
-	if err := s.Svc.ReassignTasks(c.Context()); err != nil {
+	return s.reassign(c, "")
+}
+
+// reassign shuffles the open tasks, only those of the project unless
+// projectID is empty, or just previews the reassignment on dry_run.
+func (s Server) reassign(c *fiber.Ctx, projectID string) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	dryRun, err := strconv.ParseBool(c.Query("dry_run", "false"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid dry_run value")
+	}
+
+	var plan *model.ReassignmentPlan
+	if dryRun {
+		plan, err = s.Svc.PreviewReassignment(c.Context(), projectID)
+	} else {
+		plan, err = s.Svc.ReassignTasks(c.Context(), projectID, claims.UUID)
+	}
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrNoTasksToReassign) || errors.Is(err, service.ErrNoProjectWorkers) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(plan)
+}
+
+func (s Server) applyReassignmentPlan(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	id, err := s.parseID(c)
+	if err != nil {
+		log.Println(err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	plan, err := s.Svc.ApplyReassignmentPlan(c.Context(), id, claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrPlanNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrStalePlan) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(plan)
+}
+
+func (s Server) getTaskHistory(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	history, err := s.Svc.GetTaskHistory(c.Context(), id)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(history)
+}
+
+func (s Server) getTaskTree(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	tree, err := s.Svc.GetTaskTree(c.Context(), id)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(tree)
+}
+
+func (s Server) addBlocker(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	var l model.TaskLink
+	if err := c.BodyParser(&l); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	l.BlockedID = id
+	if err := l.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	if !isUUID(l.BlockerID) {
+		return c.Status(fiber.StatusBadRequest).SendString("task id is not a valid uuid")
+	}
+
+	created, err := s.Svc.LinkTasks(c.Context(), l)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrDependencyCycle) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
 
+func (s Server) removeBlocker(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	blockerID := c.Params("blocker_id")
+	if !isUUID(blockerID) {
+		return c.Status(fiber.StatusBadRequest).SendString("task id is not a valid uuid")
+	}
+	err = s.Svc.UnlinkTasks(c.Context(), blockerID, id)
+	if errors.Is(err, service.ErrLinkNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
 	return c.SendStatus(fiber.StatusOK)
 }
 
-func (s Server) parseID(ctx *fiber.Ctx) (string, error) {
-	idParam := ctx.Params("id")
-	if idParam == "" {
-		err := fmt.Errorf("task id is empty")
+func (s Server) createAttachment(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		log.Printf("failed to get uploaded file: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	a := model.Attachment{
+		TaskID:      taskID,
+		Filename:    fh.Filename,
+		ContentType: fh.Header.Get(fiber.HeaderContentType),
+		Size:        fh.Size,
+		UploaderID:  claims.UUID,
+	}
+	if err := a.Validate(); err != nil {
+		if a.Size > model.MaxAttachmentSize {
+			return c.Status(fiber.StatusRequestEntityTooLarge).SendString(err.Error())
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	defer f.Close()
+
+	created, err := s.Svc.CreateAttachment(c.Context(), a, f)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrAttachmentTooLarge) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrUnsupportedMediaType) {
+		return c.Status(fiber.StatusUnsupportedMediaType).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (s Server) getAttachments(c *fiber.Ctx) error {
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	attachments, err := s.Svc.GetAttachments(c.Context(), taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(attachments)
+}
+
+func (s Server) getAttachment(c *fiber.Ctx) error {
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	a, content, err := s.Svc.GetAttachment(c.Context(), taskID, c.Params("attachment_id"))
+	if errors.Is(err, service.ErrTaskNotFound) || errors.Is(err, service.ErrAttachmentNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, a.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", a.Filename))
+	c.Set("X-Checksum-SHA256", a.Checksum)
+	// the content is closed once it's sent
+	return c.Status(fiber.StatusOK).SendStream(content, int(a.Size))
+}
+
+func (s Server) deleteAttachment(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.DeleteAttachment(c.Context(), taskID, c.Params("attachment_id"), claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrTaskNotFound) || errors.Is(err, service.ErrAttachmentNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s Server) watchTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.WatchTask(c.Context(), taskID, claims.UUID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s Server) unwatchTask(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.UnwatchTask(c.Context(), taskID, claims.UUID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s Server) getWatchers(c *fiber.Ctx) error {
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	watchers, err := s.Svc.GetWatchers(c.Context(), taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(watchers)
+}
+
+func (s Server) createComment(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	var cm model.Comment
+	if err := c.BodyParser(&cm); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := cm.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	cm.TaskID = taskID
+	cm.AuthorID = claims.UUID
+
+	created, err := s.Svc.CreateComment(c.Context(), cm)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (s Server) getComments(c *fiber.Ctx) error {
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	comments, err := s.Svc.GetComments(c.Context(), taskID)
+	if errors.Is(err, service.ErrTaskNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(comments)
+}
+
+func (s Server) updateComment(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	var cm model.Comment
+	if err := c.BodyParser(&cm); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := cm.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	cm.ID = c.Params("comment_id")
+	cm.TaskID = taskID
+
+	updated, err := s.Svc.UpdateComment(c.Context(), cm, claims.UUID)
+	if errors.Is(err, service.ErrTaskNotFound) || errors.Is(err, service.ErrCommentNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+func (s Server) deleteComment(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.DeleteComment(c.Context(), taskID, c.Params("comment_id"), claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrCommentNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s Server) getCommentHistory(c *fiber.Ctx) error {
+	taskID, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	edits, err := s.Svc.GetCommentEdits(c.Context(), taskID, c.Params("comment_id"))
+	if errors.Is(err, service.ErrCommentNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(edits)
+}
+
+func (s Server) createLabel(c *fiber.Ctx) error {
+	var l model.Label
+	if err := c.BodyParser(&l); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := l.ValidateCreate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	created, err := s.Svc.CreateLabel(c.Context(), l)
+	if errors.Is(err, service.ErrLabelExists) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (s Server) getLabels(c *fiber.Ctx) error {
+	labels, err := s.Svc.GetLabels(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(labels)
+}
+
+func (s Server) getLabel(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	l, err := s.Svc.GetLabelByID(c.Context(), id)
+	if errors.Is(err, service.ErrLabelNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(l)
+}
+
+func (s Server) updateLabel(c *fiber.Ctx) error {
+	var l model.Label
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := c.BodyParser(&l); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := l.ValidateUpdate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	l.ID = id
+
+	updated, err := s.Svc.UpdateLabel(c.Context(), l)
+	if errors.Is(err, service.ErrLabelNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrLabelExists) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+func (s Server) deleteLabel(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := s.Svc.DeleteLabel(c.Context(), id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s Server) createWebhook(c *fiber.Ctx) error {
+	var w model.Webhook
+	if err := c.BodyParser(&w); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := w.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	created, err := s.Svc.CreateWebhook(c.Context(), w)
+	if errors.Is(err, service.ErrUnknownEventType) {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (s Server) getWebhooks(c *fiber.Ctx) error {
+	webhooks, err := s.Svc.GetWebhooks(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(webhooks)
+}
+
+func (s Server) deleteWebhook(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.DeleteWebhook(c.Context(), id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s Server) testWebhook(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	d, err := s.Svc.TestWebhook(c.Context(), id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(d)
+}
+
+func (s Server) getWebhookDeliveries(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	deliveries, err := s.Svc.GetWebhookDeliveries(c.Context(), id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(deliveries)
+}
+
+func (s Server) getWebhookDelivery(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	d, err := s.Svc.GetWebhookDelivery(c.Context(), id, c.Params("delivery_id"))
+	if errors.Is(err, service.ErrWebhookDeliveryNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(d)
+}
+
+func (s Server) replayWebhookDelivery(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	d, err := s.Svc.ReplayWebhookDelivery(c.Context(), id, c.Params("delivery_id"))
+	if errors.Is(err, service.ErrWebhookDeliveryNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusAccepted).JSON(d)
+}
+
+func (s Server) getNotificationPreference(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	p, err := s.Svc.GetNotificationPreference(c.Context(), claims.UUID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(p)
+}
+
+func (s Server) setNotificationPreference(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	var p model.NotificationPreference
+	if err := c.BodyParser(&p); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := p.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	p.UserID = claims.UUID
+
+	updated, err := s.Svc.SetNotificationPreference(c.Context(), p)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+func (s Server) createProject(c *fiber.Ctx) error {
+	var p model.Project
+	if err := c.BodyParser(&p); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := p.ValidateCreate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	created, err := s.Svc.CreateProject(c.Context(), p)
+	if errors.Is(err, service.ErrProjectExists) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+func (s Server) getProjects(c *fiber.Ctx) error {
+	projects, err := s.Svc.GetProjects(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(projects)
+}
+
+func (s Server) getProject(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	project, err := s.Svc.GetProject(c.Context(), id)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(project)
+}
+
+func (s Server) updateProject(c *fiber.Ctx) error {
+	var p model.Project
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := c.BodyParser(&p); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := p.ValidateUpdate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+	p.ID = id
+
+	updated, err := s.Svc.UpdateProject(c.Context(), p)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+func (s Server) addProjectMembers(c *fiber.Ctx) error {
+	var m model.ProjectMembers
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := c.BodyParser(&m); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := m.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	members, err := s.Svc.AddProjectMembers(c.Context(), id, m.UserIDs)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(members)
+}
+
+func (s Server) getProjectMembers(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	members, err := s.Svc.GetProjectMembers(c.Context(), id)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(members)
+}
+
+func (s Server) removeProjectMember(c *fiber.Ctx) error {
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.RemoveProjectMember(c.Context(), id, c.Params("user_id"))
+	if errors.Is(err, service.ErrProjectNotFound) || errors.Is(err, service.ErrProjectMemberNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// getProjectTasks lists the tasks of the project to its members and admins.
+func (s Server) getProjectTasks(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	var q model.TaskListQuery
+	if err := c.QueryParser(&q); err != nil {
+		log.Printf("failed to parse query: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	f, err := q.ToFilter()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	f.ProjectID = id
+
+	err = s.Svc.CheckProjectAccess(c.Context(), id, claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	page, err := s.Svc.ListTasks(c.Context(), f)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// reassignProjectTasks reassigns the open tasks of the project. Only the
+// project members and admins may reassign them.
+func (s Server) reassignProjectTasks(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	id, err := s.parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err = s.Svc.CheckProjectAccess(c.Context(), id, claims.UUID, claims.Role == adminRole)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(fiber.StatusForbidden).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return s.reassign(c, id)
+}
+
+func (s Server) updateAssignee(c *fiber.Ctx) error {
+	var a model.Assignee
+	uuid, err := s.parseID(c)
+	if err != nil {
+		log.Println(err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := c.BodyParser(&a); err != nil {
+		log.Printf("failed to parse body: %v\n", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := a.ValidateUpdate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+	}
+
+	updated, err := s.Svc.UpdateAssigneeCapacity(c.Context(), uuid, a.Capacity)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
+
+// uuidRe matches the textual form of the UUIDs all the entities are identified by.
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isUUID reports whether the id is a UUID, so that it can be queried for.
+func isUUID(id string) bool {
+	return uuidRe.MatchString(id)
+}
+
+func (s Server) parseID(ctx *fiber.Ctx) (string, error) {
+	idParam := ctx.Params("id")
+	if idParam == "" {
+		err := fmt.Errorf("task id is empty")
+		log.Println(err)
+		return "", err
+	}
+	if !isUUID(idParam) {
+		err := fmt.Errorf("id %q is not a valid uuid", idParam)
 		log.Println(err)
 		return "", err
 	}
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/ko3luhbka/task_tracker/rest/model"
+	"github.com/ko3luhbka/task_tracker/service"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotent makes the retries of a request with the same Idempotency-Key
+// header get the response to the first request instead of being handled
+// again. Requests without the header are handled as usual.
+func (s Server) idempotent(c *fiber.Ctx) error {
+	key := c.Get(idempotencyKeyHeader)
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return c.Status(fiber.StatusBadRequest).SendString("idempotency key is too long")
+	}
+
+	// keys of different users never clash
+	endpoint := c.Method() + " " + c.Route().Path
+	if claims, ok := tokenClaims(c); ok {
+		endpoint += " " + claims.UUID
+	}
+	request := append(append([]byte(nil), c.Request().URI().QueryString()...), c.Body()...)
+
+	replay, err := s.Svc.BeginIdempotentRequest(c.Context(), key, endpoint, request)
+	if errors.Is(err, service.ErrIdempotencyKeyReused) || errors.Is(err, service.ErrRequestInProgress) {
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	if replay != nil {
+		c.Set(idempotentReplayHeader, "true")
+		c.Set(fiber.HeaderContentType, replay.ContentType)
+		return c.Status(replay.StatusCode).Send(replay.Body)
+	}
+
+	handlerErr := c.Next()
+	resp := model.IdempotentResponse{
+		StatusCode:  c.Response().StatusCode(),
+		ContentType: string(c.Response().Header.ContentType()),
+		Body:        append([]byte(nil), c.Response().Body()...),
+	}
+	if handlerErr != nil {
+		resp.StatusCode = fiber.StatusInternalServerError
+	}
+	if err := s.Svc.FinishIdempotentRequest(c.Context(), key, endpoint, resp); err != nil {
+		log.Printf("failed to store response for idempotency key %s: %v\n", key, err)
+	}
+	return handlerErr
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const (
+	MaxAttachmentSize         = 10 << 20
+	maxAttachmentFilenameSize = 255
+)
+
+// attachmentTypes are the content types attachments may have. The type is
+// sniffed from the content rather than taken from the request.
+var attachmentTypes = map[string]struct{}{
+	"text/plain":         {},
+	"application/json":   {},
+	"application/pdf":    {},
+	"application/zip":    {},
+	"application/x-gzip": {},
+	"image/png":          {},
+	"image/jpeg":         {},
+	"image/gif":          {},
+	"image/webp":         {},
+}
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum_sha256"`
+	UploaderID  string    `json:"uploader_id"`
+	Created     time.Time `json:"created"`
+}
+
+// Validate cleans the filename up to its base name and checks the limits
+// known before the content is read.
+func (a *Attachment) Validate() error {
+	a.Filename = path.Base(strings.ReplaceAll(a.Filename, `\`, "/"))
+	if a.Filename == "" || a.Filename == "." || a.Filename == "/" {
+		return fmt.Errorf("filename is empty")
+	}
+	if len(a.Filename) > maxAttachmentFilenameSize {
+		return fmt.Errorf("filename is longer than %d bytes", maxAttachmentFilenameSize)
+	}
+	if a.Size > MaxAttachmentSize {
+		return fmt.Errorf("attachment is larger than %d bytes", MaxAttachmentSize)
+	}
+	return nil
+}
+
+// IsAllowedAttachmentType tells if the content type, without its parameters,
+// is allowed for attachments.
+func IsAllowedAttachmentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	_, ok := attachmentTypes[strings.TrimSpace(mediaType)]
+	return ok
+}
+
+func (m *Attachment) ToEntity() *db.Attachment {
+	return &db.Attachment{
+		ID:          m.ID,
+		TaskID:      m.TaskID,
+		Filename:    m.Filename,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Checksum:    m.Checksum,
+		UploaderID:  m.UploaderID,
+		Created:     m.Created,
+	}
+}
+
+func (m *Attachment) FromEntity(e *db.Attachment) {
+	m.ID = e.ID
+	m.TaskID = e.TaskID
+	m.Filename = e.Filename
+	m.ContentType = e.ContentType
+	m.Size = e.Size
+	m.Checksum = e.Checksum
+	m.UploaderID = e.UploaderID
+	m.Created = e.Created
+}
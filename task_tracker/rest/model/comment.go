@@ -0,0 +1,62 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const maxCommentLength = 10000
+
+type (
+	Comment struct {
+		ID       string     `json:"id"`
+		TaskID   string     `json:"task_id"`
+		AuthorID string     `json:"author_id"`
+		Body     string     `json:"body"`
+		Created  time.Time  `json:"created"`
+		Updated  *time.Time `json:"updated,omitempty"`
+	}
+	CommentEdit struct {
+		Body     string    `json:"body"`
+		EditorID string    `json:"editor_id"`
+		Edited   time.Time `json:"edited"`
+	}
+)
+
+func (c *Comment) Validate() error {
+	if c.Body == "" {
+		return fmt.Errorf("body field is empty")
+	}
+	if len(c.Body) > maxCommentLength {
+		return fmt.Errorf("body is longer than %d bytes", maxCommentLength)
+	}
+	return nil
+}
+
+func (m *Comment) ToEntity() *db.Comment {
+	return &db.Comment{
+		ID:       m.ID,
+		TaskID:   m.TaskID,
+		AuthorID: m.AuthorID,
+		Body:     m.Body,
+		Created:  m.Created,
+		Updated:  m.Updated,
+	}
+}
+
+func (m *Comment) FromEntity(e *db.Comment) {
+	m.ID = e.ID
+	m.TaskID = e.TaskID
+	m.AuthorID = e.AuthorID
+	m.Body = e.Body
+	m.Created = e.Created
+	m.Updated = e.Updated
+}
+
+func (m *CommentEdit) FromEntity(e *db.CommentEdit) {
+	m.Body = e.Body
+	m.EditorID = e.EditorID
+	m.Edited = e.Edited
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const (
+	TaskFeedCreated    = "taskCreated"
+	TaskFeedUpdated    = "taskUpdated"
+	TaskFeedReassigned = "taskReassigned"
+	TaskFeedDeleted    = "taskDeleted"
+	TaskFeedRestored   = "taskRestored"
+)
+
+// TaskFeedEvent tells the board clients that a task has changed. ID is the
+// feed position of the event, which clients resume after.
+type TaskFeedEvent struct {
+	ID                 string          `json:"id"`
+	Kind               string          `json:"kind"`
+	PreviousAssigneeID string          `json:"previous_assignee_id,omitempty"`
+	ProjectID          string          `json:"project_id,omitempty"`
+	Task               json.RawMessage `json:"task"`
+	Created            time.Time       `json:"created"`
+}
+
+func (m *TaskFeedEvent) FromEntity(e *db.TaskFeedEntry) {
+	m.ID = FormatTaskFeedPosition(db.FeedPosition{TxID: e.TxID, ID: e.ID})
+	m.Kind = e.Kind
+	m.PreviousAssigneeID = e.PreviousAssigneeID
+	m.ProjectID = e.ProjectID
+	m.Task = e.Payload
+	m.Created = e.Created
+}
+
+// FormatTaskFeedPosition encodes the feed position as the <txid>-<id> event
+// ID sent to the clients.
+func FormatTaskFeedPosition(p db.FeedPosition) string {
+	return strconv.FormatUint(p.TxID, 10) + "-" + strconv.FormatInt(p.ID, 10)
+}
+
+// ParseTaskFeedPosition decodes an event ID made by FormatTaskFeedPosition.
+func ParseTaskFeedPosition(id string) (*db.FeedPosition, error) {
+	txID, entryID, ok := strings.Cut(id, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid last event id: %s", id)
+	}
+	p := new(db.FeedPosition)
+	var err error
+	if p.TxID, err = strconv.ParseUint(txID, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid last event id: %s", id)
+	}
+	if p.ID, err = strconv.ParseInt(entryID, 10, 64); err != nil || p.ID < 0 {
+		return nil, fmt.Errorf("invalid last event id: %s", id)
+	}
+	return p, nil
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+type TaskChange struct {
+	Field    string    `json:"field"`
+	OldValue string    `json:"old_value"`
+	NewValue string    `json:"new_value"`
+	ActorID  string    `json:"actor_id"`
+	Changed  time.Time `json:"changed"`
+}
+
+func (m *TaskChange) FromEntity(e *db.TaskChange) {
+	m.Field = e.Field
+	m.OldValue = e.OldValue
+	m.NewValue = e.NewValue
+	m.ActorID = e.ActorID
+	m.Changed = e.Changed
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+// IdempotentResponse is the response to a request made with an idempotency
+// key, which is replayed for the retries of the request.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+func (m *IdempotentResponse) FromEntity(e *db.IdempotencyKey) {
+	if e.StatusCode != nil {
+		m.StatusCode = *e.StatusCode
+	}
+	if e.ContentType != nil {
+		m.ContentType = *e.ContentType
+	}
+	m.Body = e.Response
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const maxLabelNameLength = 64
+
+var labelColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type Label struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Color   string    `json:"color"`
+	Created time.Time `json:"created"`
+}
+
+func (l *Label) ValidateCreate() error {
+	if l.Name == "" {
+		return fmt.Errorf("name field is empty")
+	}
+	return l.ValidateUpdate()
+}
+
+func (l *Label) ValidateUpdate() error {
+	if len(l.Name) > maxLabelNameLength {
+		return fmt.Errorf("name is longer than %d bytes", maxLabelNameLength)
+	}
+	if l.Color != "" && !labelColorRe.MatchString(l.Color) {
+		return fmt.Errorf("color must look like #rrggbb: %s", l.Color)
+	}
+	return nil
+}
+
+func (m *Label) ToEntity() *db.Label {
+	return &db.Label{
+		ID:      m.ID,
+		Name:    m.Name,
+		Color:   m.Color,
+		Created: m.Created,
+	}
+}
+
+func (m *Label) FromEntity(e *db.Label) {
+	m.ID = e.ID
+	m.Name = e.Name
+	m.Color = e.Color
+	m.Created = e.Created
+}
+
+// validateTaskLabels checks the label names a task refers to. Whether the
+// labels exist is checked against the catalog later on.
+func validateTaskLabels(names []string) error {
+	for _, n := range names {
+		if n == "" {
+			return fmt.Errorf("label name is empty")
+		}
+	}
+	return nil
+}
@@ -1,40 +1,105 @@
 package model
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ko3luhbka/task_tracker/db"
 )
 
 const (
-	TaskStatusAssigned  = "Assigned"
-	TaskStatusCompleted = "Completed"
+	TaskStatusOpen       = "Open"
+	TaskStatusAssigned   = "Assigned"
+	TaskStatusInProgress = "InProgress"
+	TaskStatusInReview   = "InReview"
+	TaskStatusCompleted  = "Completed"
+	TaskStatusReopened   = "Reopened"
+
+	TaskPriorityCritical = "P0"
+	TaskPriorityHigh     = "P1"
+	TaskPriorityNormal   = "P2"
+	TaskPriorityLow      = "P3"
 )
 
+// taskEstimates are the story points a task may be estimated at.
+var taskEstimates = []int{1, 2, 3, 5, 8, 13, 21}
+
+// taskTransitions lists the statuses a task is allowed to move to from the
+// given status. Open -> Assigned only happens on (re)assignment.
+var taskTransitions = map[string][]string{
+	TaskStatusOpen:       {TaskStatusAssigned},
+	TaskStatusAssigned:   {TaskStatusInProgress, TaskStatusCompleted},
+	TaskStatusInProgress: {TaskStatusInReview, TaskStatusCompleted},
+	TaskStatusInReview:   {TaskStatusInProgress, TaskStatusCompleted},
+	TaskStatusCompleted:  {TaskStatusReopened},
+	TaskStatusReopened:   {TaskStatusInProgress, TaskStatusCompleted},
+}
+
 type (
 	UserInfo struct {
 		ID       string `json:"id"`
 		Username string `json:"username"`
+		Role     string `json:"role"`
+		Email    string `json:"email"`
+	}
+	Assignee struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+		Email    string `json:"email"`
+		Capacity int    `json:"capacity"`
 	}
 	TokenClaims struct {
 		UUID string `json:"user_uuid"`
 		Role string `json:"user_role"`
 	}
 	Task struct {
-		ID          string    `json:"id"`
-		Title       string    `json:"title"`
-		JiraID      string    `json:"jira_id"`
-		Description string    `json:"description"`
-		Status      string    `json:"status"`
-		AssigneeID  string    `json:"assignee_id"`
-		Created     time.Time `json:"created"`
+		ID           string     `json:"id"`
+		Title        string     `json:"title"`
+		JiraID       string     `json:"jira_id"`
+		Description  string     `json:"description"`
+		Status       string     `json:"status"`
+		AssigneeID   string     `json:"assignee_id"`
+		AssignFee    int        `json:"assign_fee"`
+		CompleteCost int        `json:"complete_cost"`
+		DueDate      *time.Time `json:"due_date,omitempty"`
+		ParentID     string     `json:"parent_id,omitempty"`
+		ProjectID    string     `json:"project_id,omitempty"`
+		DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+		Version      int        `json:"version"`
+		Priority     string     `json:"priority"`
+		Estimate     int        `json:"estimate"`
+		Created      time.Time  `json:"created"`
+		// Labels are label names. On update, nil leaves the labels as they are
+		// and an empty list removes them all.
+		Labels []string `json:"labels"`
 	}
 	TaskInfo struct {
-		ID         string `json:"id"`
-		Title      string `json:"title"`
-		JiraID     string `json:"jira_id"`
-		AssigneeID string `json:"assignee_id"`
+		ID             string     `json:"id"`
+		Title          string     `json:"title"`
+		JiraID         string     `json:"jira_id"`
+		AssigneeID     string     `json:"assignee_id"`
+		AssignFee      int        `json:"assign_fee"`
+		CompleteCost   int        `json:"complete_cost"`
+		Status         string     `json:"status"`
+		PreviousStatus string     `json:"previous_status,omitempty"`
+		Labels         []string   `json:"labels"`
+		DueDate        *time.Time `json:"due_date,omitempty"`
+		DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+		Version        int        `json:"version"`
+		Priority       string     `json:"priority"`
+		Estimate       int        `json:"estimate"`
+		ProjectID      string     `json:"project_id,omitempty"`
+	}
+	ReassignedTaskInfo struct {
+		TaskInfo
+		PreviousAssigneeID string `json:"previous_assignee_id"`
+	}
+	ReassignmentInfo struct {
+		Tasks []ReassignedTaskInfo `json:"tasks"`
 	}
 )
 
@@ -42,7 +107,24 @@ func (u *UserInfo) ToEntity() *db.Assignee {
 	return &db.Assignee{
 		ID:       u.ID,
 		Username: u.Username,
+		Role:     u.Role,
+		Email:    u.Email,
+	}
+}
+
+func (a *Assignee) ValidateUpdate() error {
+	if a.Capacity < 1 {
+		return fmt.Errorf("capacity must be positive")
 	}
+	return nil
+}
+
+func (m *Assignee) FromEntity(e *db.Assignee) {
+	m.ID = e.ID
+	m.Username = e.Username
+	m.Role = e.Role
+	m.Email = e.Email
+	m.Capacity = e.Capacity
 }
 
 func (t *Task) ValidateCreate() error {
@@ -52,16 +134,91 @@ func (t *Task) ValidateCreate() error {
 	if t.Description == "" {
 		return fmt.Errorf("description field is empty")
 	}
-	return nil
+	// Jira IDs of project tasks are generated from the project key
+	if t.ProjectID != "" && t.JiraID != "" {
+		return fmt.Errorf("jira_id can't be set for a project task")
+	}
+	if t.Priority == "" {
+		t.Priority = TaskPriorityNormal
+	}
+	if err := t.validateDueDate(); err != nil {
+		return err
+	}
+	if err := t.validatePriority(); err != nil {
+		return err
+	}
+	return validateTaskLabels(t.Labels)
 }
 
 func (t *Task) ValidateUpdate() error {
-	for _, s := range []string{TaskStatusAssigned, TaskStatusCompleted} {
-		if t.Status == s {
-			return nil
+	if err := t.validateDueDate(); err != nil {
+		return err
+	}
+	if err := t.validatePriority(); err != nil {
+		return err
+	}
+	if err := validateTaskLabels(t.Labels); err != nil {
+		return err
+	}
+	if t.Status == "" {
+		return nil
+	}
+	if _, ok := taskTransitions[t.Status]; !ok {
+		return fmt.Errorf("wrong task status: %s", t.Status)
+	}
+	// tasks become Open or Assigned only via 'reassign tasks' button
+	if t.Status == TaskStatusOpen || t.Status == TaskStatusAssigned {
+		return fmt.Errorf("task status can't be set to %s manually", t.Status)
+	}
+	return nil
+}
+
+// validateDueDate doesn't let a task be overdue right away.
+func (t *Task) validateDueDate() error {
+	if t.DueDate != nil && !t.DueDate.After(time.Now()) {
+		return fmt.Errorf("due date must be in the future")
+	}
+	return nil
+}
+
+// validatePriority checks the priority and the estimate. Empty priority and
+// zero estimate are left to the defaults on create and unchanged on update.
+func (t *Task) validatePriority() error {
+	if t.Priority != "" && !IsTaskPriority(t.Priority) {
+		return fmt.Errorf("priority must be one of P0, P1, P2 or P3: %s", t.Priority)
+	}
+	if t.Estimate != 0 && !isTaskEstimate(t.Estimate) {
+		return fmt.Errorf("estimate must be one of %v story points: %d", taskEstimates, t.Estimate)
+	}
+	return nil
+}
+
+func IsTaskPriority(p string) bool {
+	switch p {
+	case TaskPriorityCritical, TaskPriorityHigh, TaskPriorityNormal, TaskPriorityLow:
+		return true
+	default:
+		return false
+	}
+}
+
+func isTaskEstimate(points int) bool {
+	for _, e := range taskEstimates {
+		if e == points {
+			return true
 		}
 	}
-	return fmt.Errorf("wrong task status: %s", t.Status)
+	return false
+}
+
+// CanTransition reports whether a task in status from may be moved to status to.
+func CanTransition(from, to string) bool {
+	for _, s := range taskTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
 }
 
 // don't allow to change task assignee via REST, only with 'reassign tasks' button
@@ -73,13 +230,23 @@ func (t *Task) RemoveAssignee() {
 
 func (m *Task) ToEntity() *db.Task {
 	return &db.Task{
-		ID:          m.ID,
-		Title:       m.Title,
-		JiraID:      m.JiraID,
-		Description: m.Description,
-		Status:      m.Status,
-		AssigneeID:  m.AssigneeID,
-		Created:     m.Created,
+		ID:           m.ID,
+		Title:        m.Title,
+		JiraID:       m.JiraID,
+		Description:  m.Description,
+		Status:       m.Status,
+		AssigneeID:   m.AssigneeID,
+		AssignFee:    m.AssignFee,
+		CompleteCost: m.CompleteCost,
+		DueDate:      m.DueDate,
+		ParentID:     m.ParentID,
+		ProjectID:    m.ProjectID,
+		DeletedAt:    m.DeletedAt,
+		Version:      m.Version,
+		Priority:     m.Priority,
+		Estimate:     m.Estimate,
+		Created:      m.Created,
+		Labels:       m.Labels,
 	}
 }
 
@@ -90,14 +257,80 @@ func (m *Task) FromEntity(e *db.Task) {
 	m.Description = e.Description
 	m.Status = e.Status
 	m.AssigneeID = e.AssigneeID
+	m.AssignFee = e.AssignFee
+	m.CompleteCost = e.CompleteCost
+	m.DueDate = e.DueDate
+	m.ParentID = e.ParentID
+	m.ProjectID = e.ProjectID
+	m.DeletedAt = e.DeletedAt
+	m.Version = e.Version
+	m.Priority = e.Priority
+	m.Estimate = e.Estimate
 	m.Created = e.Created
+	m.Labels = e.Labels
+}
+
+// ETag returns the entity tag of the task version.
+func (m *Task) ETag() string {
+	return strconv.Quote(strconv.Itoa(m.Version))
+}
+
+// ErrWeakETag is returned for weak entity tags, which If-Match can't be
+// compared with.
+var ErrWeakETag = errors.New("weak entity tags can't be used in If-Match")
+
+// ParseIfMatch returns the task versions the entity tags listed in the
+// If-Match header refer to. Nil is returned for the * wildcard matching any
+// version. If-Match uses the strong comparison, so weak tags are rejected
+// with ErrWeakETag.
+func ParseIfMatch(header string) ([]int, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, nil
+	}
+	var versions []int
+	for _, etag := range strings.Split(header, ",") {
+		version, err := ParseTaskETag(etag)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ParseTaskETag returns the task version the entity tag made by ETag refers to.
+func ParseTaskETag(etag string) (int, error) {
+	etag = strings.TrimSpace(etag)
+	if strings.HasPrefix(etag, "W/") {
+		return 0, fmt.Errorf("%w: %s", ErrWeakETag, etag)
+	}
+	unquoted, err := strconv.Unquote(etag)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entity tag: %s", etag)
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid entity tag: %s", etag)
+	}
+	return version, nil
 }
 
 func TaskEntityToTaskInfo(e *db.Task) *TaskInfo {
 	return &TaskInfo{
-		ID:         e.ID,
-		Title:      e.Title,
-		JiraID:     e.JiraID,
-		AssigneeID: e.AssigneeID,
+		ID:           e.ID,
+		Title:        e.Title,
+		JiraID:       e.JiraID,
+		AssigneeID:   e.AssigneeID,
+		AssignFee:    e.AssignFee,
+		CompleteCost: e.CompleteCost,
+		Status:       e.Status,
+		Labels:       e.Labels,
+		DueDate:      e.DueDate,
+		DeletedAt:    e.DeletedAt,
+		Version:      e.Version,
+		Priority:     e.Priority,
+		Estimate:     e.Estimate,
+		ProjectID:    e.ProjectID,
 	}
 }
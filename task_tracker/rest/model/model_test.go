@@ -0,0 +1,76 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    []int
+		wantErr error
+		invalid bool
+	}{
+		{header: `"3"`, want: []int{3}},
+		{header: ` "3" `, want: []int{3}},
+		{header: `"3", "5","8"`, want: []int{3, 5, 8}},
+		{header: `*`, want: nil},
+		{header: `W/"3"`, wantErr: ErrWeakETag},
+		{header: `"3", W/"5"`, wantErr: ErrWeakETag},
+		{header: `3`, invalid: true},
+		{header: `"0"`, invalid: true},
+		{header: `"3",`, invalid: true},
+		{header: `"three"`, invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIfMatch(tt.header)
+		switch {
+		case tt.wantErr != nil:
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseIfMatch(%s) error = %v, want %v", tt.header, err, tt.wantErr)
+			}
+		case tt.invalid:
+			if err == nil || errors.Is(err, ErrWeakETag) {
+				t.Errorf("ParseIfMatch(%s) error = %v, want an invalid entity tag", tt.header, err)
+			}
+		case err != nil:
+			t.Errorf("ParseIfMatch(%s) error = %v", tt.header, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("ParseIfMatch(%s) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{from: TaskStatusOpen, to: TaskStatusAssigned, want: true},
+		{from: TaskStatusOpen, to: TaskStatusInProgress, want: false},
+		{from: TaskStatusAssigned, to: TaskStatusInProgress, want: true},
+		{from: TaskStatusAssigned, to: TaskStatusCompleted, want: true},
+		{from: TaskStatusAssigned, to: TaskStatusInReview, want: false},
+		{from: TaskStatusInProgress, to: TaskStatusInReview, want: true},
+		{from: TaskStatusInProgress, to: TaskStatusCompleted, want: true},
+		{from: TaskStatusInProgress, to: TaskStatusOpen, want: false},
+		{from: TaskStatusInReview, to: TaskStatusInProgress, want: true},
+		{from: TaskStatusInReview, to: TaskStatusCompleted, want: true},
+		{from: TaskStatusCompleted, to: TaskStatusReopened, want: true},
+		{from: TaskStatusCompleted, to: TaskStatusInProgress, want: false},
+		{from: TaskStatusReopened, to: TaskStatusInProgress, want: true},
+		{from: TaskStatusReopened, to: TaskStatusCompleted, want: true},
+		{from: TaskStatusReopened, to: TaskStatusAssigned, want: false},
+		{from: TaskStatusCompleted, to: TaskStatusCompleted, want: false},
+		{from: "Unknown", to: TaskStatusCompleted, want: false},
+		{from: TaskStatusAssigned, to: "Unknown", want: false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %t, want %t", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
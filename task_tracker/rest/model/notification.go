@@ -0,0 +1,56 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const (
+	NotifyByEmail  = "email"
+	NotifyByDigest = "digest"
+	NotifyOff      = "off"
+)
+
+type (
+	Watcher struct {
+		UserID  string    `json:"user_id"`
+		Created time.Time `json:"created"`
+	}
+	NotificationPreference struct {
+		UserID  string     `json:"user_id"`
+		Mode    string     `json:"mode"`
+		Updated *time.Time `json:"updated,omitempty"`
+	}
+	// Notification asks the mailer to tell the recipient about a task.
+	Notification struct {
+		RecipientID string   `json:"recipient_id"`
+		Delivery    string   `json:"delivery"`
+		Task        TaskRef  `json:"task"`
+		Change      string   `json:"change"`
+		Fields      []string `json:"fields,omitempty"`
+		ActorID     string   `json:"actor_id,omitempty"`
+	}
+)
+
+func (p *NotificationPreference) Validate() error {
+	switch p.Mode {
+	case NotifyByEmail, NotifyByDigest, NotifyOff:
+		return nil
+	default:
+		return fmt.Errorf("mode must be one of %s, %s or %s: %s", NotifyByEmail, NotifyByDigest, NotifyOff, p.Mode)
+	}
+}
+
+func (m *NotificationPreference) FromEntity(e *db.NotificationPreference) {
+	m.UserID = e.UserID
+	m.Mode = e.Mode
+	updated := e.Updated
+	m.Updated = &updated
+}
+
+func (m *Watcher) FromEntity(e *db.Watcher) {
+	m.UserID = e.UserID
+	m.Created = e.Created
+}
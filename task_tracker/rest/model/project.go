@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const maxProjectNameLength = 255
+
+// projectKeyRe matches the project keys the Jira IDs of the project tasks
+// are prefixed with, such as POP.
+var projectKeyRe = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+type (
+	Project struct {
+		ID          string    `json:"id"`
+		Key         string    `json:"key"`
+		Name        string    `json:"name"`
+		Description string    `json:"description"`
+		Created     time.Time `json:"created"`
+	}
+	ProjectMember struct {
+		UserID  string    `json:"user_id"`
+		Created time.Time `json:"created"`
+	}
+	// ProjectMembers is the body of the request adding members to a project.
+	ProjectMembers struct {
+		UserIDs []string `json:"user_ids"`
+	}
+)
+
+func (p *Project) ValidateCreate() error {
+	if !projectKeyRe.MatchString(p.Key) {
+		return fmt.Errorf("key must be 2 to 10 uppercase letters or digits starting with a letter: %s", p.Key)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("name field is empty")
+	}
+	return p.validateName()
+}
+
+func (p *Project) ValidateUpdate() error {
+	// the key is a part of the Jira IDs already given to the project tasks
+	if p.Key != "" {
+		return fmt.Errorf("key can't be changed")
+	}
+	return p.validateName()
+}
+
+func (p *Project) validateName() error {
+	if len(p.Name) > maxProjectNameLength {
+		return fmt.Errorf("name is longer than %d bytes", maxProjectNameLength)
+	}
+	return nil
+}
+
+func (m *ProjectMembers) Validate() error {
+	if len(m.UserIDs) == 0 {
+		return fmt.Errorf("user_ids field is empty")
+	}
+	for _, id := range m.UserIDs {
+		if id == "" {
+			return fmt.Errorf("user id is empty")
+		}
+	}
+	return nil
+}
+
+func (m *Project) ToEntity() *db.Project {
+	return &db.Project{
+		ID:          m.ID,
+		Key:         m.Key,
+		Name:        m.Name,
+		Description: m.Description,
+		Created:     m.Created,
+	}
+}
+
+func (m *Project) FromEntity(e *db.Project) {
+	m.ID = e.ID
+	m.Key = e.Key
+	m.Name = e.Name
+	m.Description = e.Description
+	m.Created = e.Created
+}
+
+func (m *ProjectMember) FromEntity(e *db.ProjectMember) {
+	m.UserID = e.UserID
+	m.Created = e.Created
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+type (
+	PlannedAssignment struct {
+		TaskID             string `json:"task_id"`
+		Title              string `json:"title"`
+		JiraID             string `json:"jira_id"`
+		PreviousAssigneeID string `json:"previous_assignee_id"`
+		AssigneeID         string `json:"assignee_id"`
+		AssignFee          int    `json:"assign_fee"`
+	}
+	AssigneeFees struct {
+		AssigneeID string `json:"assignee_id"`
+		Tasks      int    `json:"tasks"`
+		TotalFee   int    `json:"total_fee"`
+	}
+	// ReassignmentPlan describes which assignee every open task goes to and
+	// how much each assignee is going to be charged for that. The plan of a
+	// project reassignment only covers the open tasks of the project.
+	ReassignmentPlan struct {
+		ID          string              `json:"id,omitempty"`
+		ProjectID   string              `json:"project_id,omitempty"`
+		Created     time.Time           `json:"created"`
+		Assignments []PlannedAssignment `json:"assignments"`
+		Fees        []AssigneeFees      `json:"fees"`
+	}
+)
+
+// NewReassignmentPlan builds a plan from the assignments and sums up the fees.
+func NewReassignmentPlan(projectID string, assignments []PlannedAssignment) *ReassignmentPlan {
+	p := &ReassignmentPlan{
+		ProjectID:   projectID,
+		Created:     time.Now().UTC(),
+		Assignments: assignments,
+	}
+	p.sumFees()
+	return p
+}
+
+func (p *ReassignmentPlan) sumFees() {
+	fees := make(map[string]*AssigneeFees)
+	for _, a := range p.Assignments {
+		f, ok := fees[a.AssigneeID]
+		if !ok {
+			f = &AssigneeFees{AssigneeID: a.AssigneeID}
+			fees[a.AssigneeID] = f
+		}
+		f.Tasks++
+		f.TotalFee += a.AssignFee
+	}
+
+	p.Fees = make([]AssigneeFees, 0, len(fees))
+	for _, f := range fees {
+		p.Fees = append(p.Fees, *f)
+	}
+	sort.Slice(p.Fees, func(i, j int) bool {
+		return p.Fees[i].AssigneeID < p.Fees[j].AssigneeID
+	})
+}
+
+// CheckUpToDate returns an error if the open tasks are not the ones the plan
+// was made for, or some of them have been reassigned since then.
+func (p *ReassignmentPlan) CheckUpToDate(openTasks []db.Task) error {
+	if len(openTasks) != len(p.Assignments) {
+		return fmt.Errorf("plan covers %d tasks, but %d tasks are open now", len(p.Assignments), len(openTasks))
+	}
+
+	planned := make(map[string]string, len(p.Assignments))
+	for _, a := range p.Assignments {
+		planned[a.TaskID] = a.PreviousAssigneeID
+	}
+	for _, t := range openTasks {
+		prevAssigneeID, ok := planned[t.ID]
+		if !ok {
+			return fmt.Errorf("task %s is not covered by the plan", t.ID)
+		}
+		if prevAssigneeID != t.AssigneeID {
+			return fmt.Errorf("task %s has been reassigned since the plan was made", t.ID)
+		}
+	}
+	return nil
+}
+
+func (p *ReassignmentPlan) ToEntity() (*db.ReassignmentPlan, error) {
+	assignments, err := json.Marshal(p.Assignments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plan assignments: %v", err)
+	}
+	return &db.ReassignmentPlan{
+		ID:          p.ID,
+		ProjectID:   p.ProjectID,
+		Assignments: assignments,
+		Created:     p.Created,
+	}, nil
+}
+
+func (p *ReassignmentPlan) FromEntity(e *db.ReassignmentPlan) error {
+	p.ID = e.ID
+	p.ProjectID = e.ProjectID
+	p.Created = e.Created
+	if err := json.Unmarshal(e.Assignments, &p.Assignments); err != nil {
+		return fmt.Errorf("failed to unmarshal plan assignments: %v", err)
+	}
+	p.sumFees()
+	return nil
+}
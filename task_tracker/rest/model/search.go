@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const (
+	maxSearchQueryLength  = 256
+	defaultSearchLimit    = 20
+	maxSearchResultsLimit = 100
+)
+
+type (
+	TaskSearchQuery struct {
+		Query string `query:"q"`
+		Limit int    `query:"limit"`
+	}
+	// TaskSearchHit is a found task. Snippet is HTML with the matches
+	// enclosed in <b> tags, the task text in it is escaped.
+	TaskSearchHit struct {
+		Task    Task    `json:"task"`
+		Rank    float64 `json:"rank"`
+		Snippet string  `json:"snippet"`
+	}
+)
+
+var snippetHighlighter = strings.NewReplacer(db.SnippetStartSel, "<b>", db.SnippetStopSel, "</b>")
+
+func (q *TaskSearchQuery) Validate() error {
+	if q.Query == "" {
+		return fmt.Errorf("q parameter is empty")
+	}
+	if len(q.Query) > maxSearchQueryLength {
+		return fmt.Errorf("q is longer than %d bytes", maxSearchQueryLength)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > maxSearchResultsLimit {
+		q.Limit = maxSearchResultsLimit
+	}
+	return nil
+}
+
+func (m *TaskSearchHit) FromEntity(e *db.TaskSearchHit) {
+	m.Task.FromEntity(&e.Task)
+	m.Rank = e.Rank
+	m.Snippet = snippetHighlighter.Replace(html.EscapeString(e.Snippet))
+}
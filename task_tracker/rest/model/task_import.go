@@ -0,0 +1,239 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TaskFormatCSV  = "csv"
+	TaskFormatJSON = "json"
+
+	// labels are separated by this in the labels CSV column
+	csvLabelSeparator = ";"
+	maxImportRows     = 5000
+)
+
+// taskCSVColumns are the columns of exported tasks. Imported CSV files may have
+// any of them, so that an export can be imported back, but only title,
+// jira_id, description, due_date, priority, estimate and labels are taken into
+// account.
+var taskCSVColumns = []string{
+	"id", "title", "jira_id", "description", "status", "assignee_id",
+	"assign_fee", "complete_cost", "due_date", "priority", "estimate",
+	"labels", "created",
+}
+
+type (
+	// ImportRow is a task parsed from an import file. Line is the number of the
+	// line it comes from, Err is set if the line can't be parsed.
+	ImportRow struct {
+		Line int
+		Task Task
+		Err  error
+	}
+	ImportRowResult struct {
+		Line   int    `json:"line"`
+		TaskID string `json:"task_id,omitempty"`
+		Error  string `json:"error,omitempty"`
+	}
+	ImportReport struct {
+		DryRun   bool              `json:"dry_run"`
+		Accepted int               `json:"accepted"`
+		Rejected int               `json:"rejected"`
+		Rows     []ImportRowResult `json:"rows"`
+	}
+)
+
+// ValidateTaskFormat returns an error unless format is one of TaskFormat*.
+func ValidateTaskFormat(format string) error {
+	if format != TaskFormatCSV && format != TaskFormatJSON {
+		return fmt.Errorf("unknown format %q, expected %s or %s", format, TaskFormatCSV, TaskFormatJSON)
+	}
+	return nil
+}
+
+// ParseTaskImport reads tasks from a CSV file with a header line or from JSON
+// lines, one task per line. An error is returned only if the input as a whole
+// can't be read, errors of single rows are reported in their ImportRow.
+func ParseTaskImport(r io.Reader, format string) ([]ImportRow, error) {
+	var rows []ImportRow
+	var err error
+	switch format {
+	case TaskFormatCSV:
+		rows, err = parseTaskCSV(r)
+	case TaskFormatJSON:
+		rows, err = parseTaskJSONLines(r)
+	default:
+		err = ValidateTaskFormat(format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("too many rows: %d, at most %d are allowed", len(rows), maxImportRows)
+	}
+	return rows, nil
+}
+
+func parseTaskCSV(r io.Reader) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("CSV header is missing")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if !isTaskCSVColumn(name) {
+			return nil, fmt.Errorf("unknown CSV column: %s", name)
+		}
+		columns[name] = i
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		line, _ := cr.FieldPos(0)
+		row := ImportRow{Line: line}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %v", err)
+			}
+			row.Line = parseErr.Line
+			row.Err = err
+		} else if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+		} else {
+			row.Task, row.Err = taskFromCSVRecord(record, columns)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func isTaskCSVColumn(name string) bool {
+	for _, c := range taskCSVColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func taskFromCSVRecord(record []string, columns map[string]int) (Task, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	t := Task{
+		Title:       field("title"),
+		JiraID:      field("jira_id"),
+		Description: field("description"),
+		Priority:    field("priority"),
+	}
+	if due := field("due_date"); due != "" {
+		dueDate, err := time.Parse(time.RFC3339, due)
+		if err != nil {
+			return t, fmt.Errorf("invalid due_date: %v", err)
+		}
+		t.DueDate = &dueDate
+	}
+	if estimate := field("estimate"); estimate != "" {
+		points, err := strconv.Atoi(estimate)
+		if err != nil {
+			return t, fmt.Errorf("invalid estimate: %v", err)
+		}
+		t.Estimate = points
+	}
+	if labels := field("labels"); labels != "" {
+		for _, l := range strings.Split(labels, csvLabelSeparator) {
+			t.Labels = append(t.Labels, strings.TrimSpace(l))
+		}
+	}
+	return t, nil
+}
+
+func parseTaskJSONLines(r io.Reader) ([]ImportRow, error) {
+	var rows []ImportRow
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		row := ImportRow{Line: line}
+		var t Task
+		if err := json.Unmarshal(b, &t); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %v", err)
+		} else {
+			// only the fields a new task may be created with are taken
+			row.Task = Task{
+				Title:       t.Title,
+				JiraID:      t.JiraID,
+				Description: t.Description,
+				DueDate:     t.DueDate,
+				Priority:    t.Priority,
+				Estimate:    t.Estimate,
+				Labels:      t.Labels,
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON lines: %v", err)
+	}
+	return rows, nil
+}
+
+// TaskCSVHeader returns the header line of exported CSV files.
+func TaskCSVHeader() []string {
+	return taskCSVColumns
+}
+
+// CSVRecord returns the task fields in the order of TaskCSVHeader.
+func (m *Task) CSVRecord() []string {
+	var dueDate string
+	if m.DueDate != nil {
+		dueDate = m.DueDate.Format(time.RFC3339)
+	}
+	return []string{
+		m.ID,
+		m.Title,
+		m.JiraID,
+		m.Description,
+		m.Status,
+		m.AssigneeID,
+		strconv.Itoa(m.AssignFee),
+		strconv.Itoa(m.CompleteCost),
+		dueDate,
+		m.Priority,
+		strconv.Itoa(m.Estimate),
+		strings.Join(m.Labels, csvLabelSeparator),
+		m.Created.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTaskImport(t *testing.T) {
+	due := time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC)
+
+	// wantRow is an expected row, wantErr tells whether the row itself is invalid
+	type wantRow struct {
+		line    int
+		task    Task
+		wantErr bool
+	}
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		want    []wantRow
+		wantErr bool
+	}{
+		{
+			name:   "csv",
+			format: TaskFormatCSV,
+			input: "title,jira_id,due_date,priority,estimate,labels\n" +
+				"Feed the parrots,POP-1,2022-11-20T12:00:00Z,P1,3, bug ; urgent\n" +
+				"Clean the cage,,,,,\n",
+			want: []wantRow{
+				{line: 2, task: Task{
+					Title:    "Feed the parrots",
+					JiraID:   "POP-1",
+					DueDate:  &due,
+					Priority: "P1",
+					Estimate: 3,
+					Labels:   []string{"bug", "urgent"},
+				}},
+				{line: 3, task: Task{Title: "Clean the cage"}},
+			},
+		},
+		{
+			name:   "csv export columns are ignored",
+			format: TaskFormatCSV,
+			input: "id,title,status,assignee_id,assign_fee\n" +
+				"f2b9c3d4-0000-0000-0000-000000000000,Feed the parrots,Completed,someone,20\n",
+			want: []wantRow{
+				{line: 2, task: Task{Title: "Feed the parrots"}},
+			},
+		},
+		{
+			name:   "csv invalid rows",
+			format: TaskFormatCSV,
+			input: "title,estimate,due_date\n" +
+				"Feed the parrots,three,\n" +
+				"Clean the cage,,tomorrow\n" +
+				"Too few fields\n" +
+				"Water the plants,2,\n",
+			want: []wantRow{
+				{line: 2, wantErr: true},
+				{line: 3, wantErr: true},
+				{line: 4, wantErr: true},
+				{line: 5, task: Task{Title: "Water the plants", Estimate: 2}},
+			},
+		},
+		{
+			name:    "csv unknown column",
+			format:  TaskFormatCSV,
+			input:   "title,color\nFeed the parrots,green\n",
+			wantErr: true,
+		},
+		{
+			name:    "csv without header",
+			format:  TaskFormatCSV,
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:   "json lines",
+			format: TaskFormatJSON,
+			input: `{"title":"Feed the parrots","due_date":"2022-11-20T12:00:00Z","estimate":5,"labels":["bug"]}` + "\n" +
+				"\n" +
+				`{"title":"Clean the cage","status":"Completed","assignee_id":"someone","project_id":"p"}` + "\n" +
+				`{"title":` + "\n",
+			want: []wantRow{
+				{line: 1, task: Task{
+					Title:    "Feed the parrots",
+					DueDate:  &due,
+					Estimate: 5,
+					Labels:   []string{"bug"},
+				}},
+				{line: 3, task: Task{Title: "Clean the cage"}},
+				{line: 4, wantErr: true},
+			},
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			input:   "<task/>",
+			wantErr: true,
+		},
+		{
+			name:    "too many rows",
+			format:  TaskFormatJSON,
+			input:   strings.Repeat(`{"title":"Feed the parrots"}`+"\n", maxImportRows+1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseTaskImport(strings.NewReader(tt.input), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTaskImport() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("ParseTaskImport() returned %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := rows[i]
+				if got.Line != want.line {
+					t.Errorf("row %d: line = %d, want %d", i, got.Line, want.line)
+				}
+				if (got.Err != nil) != want.wantErr {
+					t.Errorf("row %d: error = %v, wantErr %t", i, got.Err, want.wantErr)
+				}
+				if !want.wantErr && !reflect.DeepEqual(got.Task, want.task) {
+					t.Errorf("row %d: task = %+v, want %+v", i, got.Task, want.task)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskCSVRecord(t *testing.T) {
+	due := time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2022, 11, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		task Task
+		want []string
+	}{
+		{
+			name: "all fields",
+			task: Task{
+				ID:           "f2b9c3d4-0000-0000-0000-000000000000",
+				Title:        "Feed the parrots",
+				JiraID:       "POP-1",
+				Description:  "twice a day, with seeds",
+				Status:       TaskStatusAssigned,
+				AssigneeID:   "e1a8b2c3-0000-0000-0000-000000000000",
+				AssignFee:    15,
+				CompleteCost: 30,
+				DueDate:      &due,
+				Priority:     TaskPriorityHigh,
+				Estimate:     3,
+				Labels:       []string{"bug", "urgent"},
+				Created:      created,
+			},
+			want: []string{
+				"f2b9c3d4-0000-0000-0000-000000000000", "Feed the parrots", "POP-1",
+				"twice a day, with seeds", "Assigned", "e1a8b2c3-0000-0000-0000-000000000000",
+				"15", "30", "2022-11-20T12:00:00Z", "P1", "3", "bug;urgent", "2022-11-01T09:30:00Z",
+			},
+		},
+		{
+			name: "empty optional fields",
+			task: Task{
+				ID:      "f2b9c3d4-0000-0000-0000-000000000000",
+				Title:   "Clean the cage",
+				Status:  TaskStatusOpen,
+				Created: created,
+			},
+			want: []string{
+				"f2b9c3d4-0000-0000-0000-000000000000", "Clean the cage", "",
+				"", "Open", "",
+				"0", "0", "", "", "0", "", "2022-11-01T09:30:00Z",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.task.CSVRecord()
+			if len(got) != len(TaskCSVHeader()) {
+				t.Fatalf("CSVRecord() has %d fields, the header has %d", len(got), len(TaskCSVHeader()))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CSVRecord() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
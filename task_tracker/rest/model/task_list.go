@@ -0,0 +1,189 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const (
+	defaultTaskSort      = "-created"
+	defaultTaskPageLimit = 50
+	maxTaskPageLimit     = 200
+)
+
+var titlePatternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type (
+	// TaskListQuery holds the query parameters of the task list endpoints.
+	// Sort is a field name, optionally prefixed with '-' for descending order.
+	TaskListQuery struct {
+		Status      string `query:"status"`
+		AssigneeID  string `query:"assignee_id"`
+		ProjectID   string `query:"project_id"`
+		JiraID      string `query:"jira_id"`
+		Title       string `query:"title"`
+		Label       string `query:"label"`
+		Priority    string `query:"priority"`
+		MinEstimate int    `query:"min_estimate"`
+		MaxEstimate int    `query:"max_estimate"`
+		CreatedFrom string `query:"created_from"`
+		CreatedTo   string `query:"created_to"`
+		Sort        string `query:"sort"`
+		Limit       int    `query:"limit"`
+		Cursor      string `query:"cursor"`
+		// IncludeDeleted lists soft deleted tasks along with the others
+		IncludeDeleted bool `query:"include_deleted"`
+	}
+	TaskPage struct {
+		Tasks []Task `json:"tasks"`
+		Next  string `json:"next,omitempty"`
+	}
+	// taskCursor points at the last task of a page. It's handed out to clients
+	// as an opaque base64 token.
+	taskCursor struct {
+		Sort  string `json:"s"`
+		Value string `json:"v"`
+		ID    string `json:"id"`
+	}
+)
+
+// ToFilter validates the query and converts it to a task filter.
+func (q *TaskListQuery) ToFilter() (*db.TaskFilter, error) {
+	f := &db.TaskFilter{
+		Status:      q.Status,
+		AssigneeID:  q.AssigneeID,
+		ProjectID:   q.ProjectID,
+		JiraID:      q.JiraID,
+		Title:       titlePatternEscaper.Replace(q.Title),
+		Label:       q.Label,
+		Priority:    q.Priority,
+		MinEstimate: q.MinEstimate,
+		MaxEstimate: q.MaxEstimate,
+		Limit:       q.Limit,
+
+		IncludeDeleted: q.IncludeDeleted,
+	}
+
+	if q.Status != "" {
+		if _, ok := taskTransitions[q.Status]; !ok {
+			return nil, fmt.Errorf("wrong task status: %s", q.Status)
+		}
+	}
+
+	if q.Priority != "" && !IsTaskPriority(q.Priority) {
+		return nil, fmt.Errorf("wrong task priority: %s", q.Priority)
+	}
+	if q.MinEstimate < 0 || q.MaxEstimate < 0 {
+		return nil, fmt.Errorf("estimate bounds can't be negative")
+	}
+
+	if q.CreatedFrom != "" {
+		from, err := time.Parse(time.RFC3339, q.CreatedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_from: %v", err)
+		}
+		f.CreatedFrom = &from
+	}
+	if q.CreatedTo != "" {
+		to, err := time.Parse(time.RFC3339, q.CreatedTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_to: %v", err)
+		}
+		f.CreatedTo = &to
+	}
+
+	if q.Sort == "" {
+		q.Sort = defaultTaskSort
+	}
+	f.SortBy = strings.TrimPrefix(q.Sort, "-")
+	f.Desc = strings.HasPrefix(q.Sort, "-")
+	if _, ok := db.TaskSortColumns[f.SortBy]; !ok {
+		return nil, fmt.Errorf("unknown sort field: %s", f.SortBy)
+	}
+
+	if f.Limit <= 0 {
+		f.Limit = defaultTaskPageLimit
+	}
+	if f.Limit > maxTaskPageLimit {
+		f.Limit = maxTaskPageLimit
+	}
+
+	if q.Cursor != "" {
+		cur, err := decodeTaskCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cur.Sort != q.Sort {
+			return nil, fmt.Errorf("cursor was issued for sort %q, not %q", cur.Sort, q.Sort)
+		}
+		f.CursorValue = cur.Value
+		f.CursorID = cur.ID
+	}
+	return f, nil
+}
+
+// IsPaginated reports whether the client asks for a page of tasks rather than
+// all of them.
+func (q *TaskListQuery) IsPaginated() bool {
+	return q.Limit != 0 || q.Cursor != ""
+}
+
+// ToExportFilter is like ToFilter but fetches the tasks in the biggest pages
+// possible, since all of them are going to be exported anyway.
+func (q *TaskListQuery) ToExportFilter() (*db.TaskFilter, error) {
+	f, err := q.ToFilter()
+	if err != nil {
+		return nil, err
+	}
+	f.Limit = maxTaskPageLimit
+	return f, nil
+}
+
+// NextTaskCursor returns the token pointing right after task t in the order
+// defined by filter f.
+func NextTaskCursor(f *db.TaskFilter, t *db.Task) string {
+	cur := taskCursor{
+		Sort: f.SortBy,
+	}
+	if f.Desc {
+		cur.Sort = "-" + cur.Sort
+	}
+	cur.Value, cur.ID = TaskCursorPosition(f, t)
+
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// TaskCursorPosition returns the values of the filter cursor fields pointing
+// right after task t.
+func TaskCursorPosition(f *db.TaskFilter, t *db.Task) (value, id string) {
+	switch f.SortBy {
+	case "created":
+		value = t.Created.Format(time.RFC3339Nano)
+	case "title":
+		value = t.Title
+	case "priority":
+		value = t.Priority
+	case "estimate":
+		value = strconv.Itoa(t.Estimate)
+	}
+	return value, t.ID
+}
+
+func decodeTaskCursor(token string) (*taskCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	var cur taskCursor
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	return &cur, nil
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+type (
+	TaskRef struct {
+		ID     string `json:"id"`
+		Title  string `json:"title"`
+		JiraID string `json:"jira_id"`
+		Status string `json:"status"`
+	}
+	// TaskTree is a task along with its subtasks and the tasks it's linked to.
+	TaskTree struct {
+		TaskRef
+		BlockedBy []TaskRef  `json:"blocked_by"`
+		Blocks    []TaskRef  `json:"blocks"`
+		Subtasks  []TaskTree `json:"subtasks"`
+	}
+	TaskLink struct {
+		BlockerID string    `json:"blocker_id"`
+		BlockedID string    `json:"blocked_id"`
+		Created   time.Time `json:"created"`
+	}
+)
+
+func TaskEntityToTaskRef(e *db.Task) *TaskRef {
+	return &TaskRef{
+		ID:     e.ID,
+		Title:  e.Title,
+		JiraID: e.JiraID,
+		Status: e.Status,
+	}
+}
+
+// NewTaskTree builds the tree rooted at rootID. subtree must contain the root
+// task and all of its descendants, linked must contain every task any of the
+// links refers to.
+func NewTaskTree(rootID string, subtree []db.Task, links []db.TaskLink, linked []db.Task) *TaskTree {
+	refs := make(map[string]*TaskRef, len(linked))
+	for i := range linked {
+		refs[linked[i].ID] = TaskEntityToTaskRef(&linked[i])
+	}
+
+	children := make(map[string][]*db.Task)
+	var root *db.Task
+	for i := range subtree {
+		t := &subtree[i]
+		if t.ID == rootID {
+			root = t
+			continue
+		}
+		children[t.ParentID] = append(children[t.ParentID], t)
+	}
+	if root == nil {
+		return nil
+	}
+
+	var build func(t *db.Task) TaskTree
+	build = func(t *db.Task) TaskTree {
+		node := TaskTree{
+			TaskRef:   *TaskEntityToTaskRef(t),
+			BlockedBy: []TaskRef{},
+			Blocks:    []TaskRef{},
+			Subtasks:  []TaskTree{},
+		}
+		for _, l := range links {
+			if l.BlockedID == t.ID && refs[l.BlockerID] != nil {
+				node.BlockedBy = append(node.BlockedBy, *refs[l.BlockerID])
+			}
+			if l.BlockerID == t.ID && refs[l.BlockedID] != nil {
+				node.Blocks = append(node.Blocks, *refs[l.BlockedID])
+			}
+		}
+		for _, c := range children[t.ID] {
+			node.Subtasks = append(node.Subtasks, build(c))
+		}
+		return node
+	}
+
+	tree := build(root)
+	return &tree
+}
+
+func (l *TaskLink) Validate() error {
+	if l.BlockerID == "" {
+		return fmt.Errorf("blocker_id field is empty")
+	}
+	if l.BlockerID == l.BlockedID {
+		return fmt.Errorf("task can't block itself")
+	}
+	return nil
+}
+
+func (m *TaskLink) ToEntity() *db.TaskLink {
+	return &db.TaskLink{
+		BlockerID: m.BlockerID,
+		BlockedID: m.BlockedID,
+		Created:   m.Created,
+	}
+}
+
+func (m *TaskLink) FromEntity(e *db.TaskLink) {
+	m.BlockerID = e.BlockerID
+	m.BlockedID = e.BlockedID
+	m.Created = e.Created
+}
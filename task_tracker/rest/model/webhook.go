@@ -0,0 +1,105 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+type (
+	Webhook struct {
+		ID         string   `json:"id"`
+		URL        string   `json:"url"`
+		EventTypes []string `json:"event_types"`
+		// Secret is only shown once the webhook is created
+		Secret  string    `json:"secret,omitempty"`
+		Active  bool      `json:"active"`
+		Created time.Time `json:"created"`
+	}
+	WebhookDelivery struct {
+		ID             string                   `json:"id"`
+		SubscriptionID string                   `json:"subscription_id"`
+		EventName      string                   `json:"event_name"`
+		Payload        json.RawMessage          `json:"payload"`
+		Status         string                   `json:"status"`
+		Attempts       int                      `json:"attempts"`
+		NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+		LastStatusCode *int                     `json:"last_status_code,omitempty"`
+		LastError      string                   `json:"last_error,omitempty"`
+		Created        time.Time                `json:"created"`
+		DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+		AttemptLog     []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+	}
+	WebhookDeliveryAttempt struct {
+		StatusCode *int      `json:"status_code,omitempty"`
+		Error      string    `json:"error,omitempty"`
+		DurationMs int       `json:"duration_ms"`
+		Created    time.Time `json:"created"`
+	}
+)
+
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) url: %s", w.URL)
+	}
+	if len(w.EventTypes) == 0 {
+		return fmt.Errorf("event_types field is empty")
+	}
+	return nil
+}
+
+func (m *Webhook) ToEntity() (*db.WebhookSubscription, error) {
+	eventTypes, err := json.Marshal(m.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+	return &db.WebhookSubscription{
+		ID:         m.ID,
+		URL:        m.URL,
+		EventTypes: eventTypes,
+		Secret:     m.Secret,
+		Active:     m.Active,
+		Created:    m.Created,
+	}, nil
+}
+
+// FromEntity fills the webhook in, except for its secret.
+func (m *Webhook) FromEntity(e *db.WebhookSubscription) error {
+	m.ID = e.ID
+	m.URL = e.URL
+	m.Active = e.Active
+	m.Created = e.Created
+	return json.Unmarshal(e.EventTypes, &m.EventTypes)
+}
+
+func (m *WebhookDelivery) FromEntity(e *db.WebhookDelivery) {
+	m.ID = e.ID
+	m.SubscriptionID = e.SubscriptionID
+	m.EventName = e.EventName
+	m.Payload = e.Payload
+	m.Status = e.Status
+	m.Attempts = e.Attempts
+	m.NextAttemptAt = nil
+	if e.Status == db.WebhookDeliveryPending {
+		next := e.NextAttemptAt
+		m.NextAttemptAt = &next
+	}
+	m.LastStatusCode = e.LastStatusCode
+	m.LastError = e.LastError
+	m.Created = e.Created
+	m.DeliveredAt = e.DeliveredAt
+}
+
+func (m *WebhookDeliveryAttempt) FromEntity(e *db.WebhookDeliveryAttempt) {
+	m.StatusCode = e.StatusCode
+	m.Error = e.Error
+	m.DurationMs = e.DurationMs
+	m.Created = e.Created
+}
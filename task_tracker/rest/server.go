@@ -6,8 +6,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 
-	"github.com/ko3luhbka/task_tracker/db"
 	"github.com/ko3luhbka/task_tracker/mq"
+	"github.com/ko3luhbka/task_tracker/rest/model"
 	"github.com/ko3luhbka/task_tracker/service"
 )
 
@@ -19,22 +19,30 @@ const (
 type Server struct {
 	Svc *service.Service
 	app *fiber.App
+	// done is closed on shutdown to end the open streams
+	done chan struct{}
 }
 
-func NewServer(tr *db.TaskRepo, ar *db.AssigneeRepo, mq *mq.Client) (*Server, error) {
+func NewServer(cfg *service.Config, repos *service.Repos, mq *mq.Client) (*Server, error) {
 	var appCfg = fiber.Config{
 		CaseSensitive: true,
 		StrictRouting: false,
+		// leave room for the multipart overhead of the largest attachment
+		BodyLimit: model.MaxAttachmentSize + 1<<20,
 	}
 
 	app := fiber.New(appCfg)
 	app.Use(logger.New())
 
-	svc := service.NewService(tr, ar, mq)
+	svc, err := service.NewService(cfg, repos, mq)
+	if err != nil {
+		return nil, err
+	}
 
 	srv := &Server{
-		Svc: svc,
-		app: app,
+		Svc:  svc,
+		app:  app,
+		done: make(chan struct{}),
 	}
 
 	srv.initRoutes()
@@ -50,6 +58,7 @@ func (s Server) Run(errCh chan<- error) {
 }
 
 func (s Server) Shutdown() error {
+	close(s.done)
 	return s.app.Shutdown()
 }
 
@@ -58,12 +67,69 @@ func (s Server) initRoutes() {
 	base.Get("/ping", s.ping)
 
 	tasks := base.Group("tasks")
-	tasks.Post("/", s.createTask)
+	tasks.Post("/", authenticated, s.idempotent, s.createTask)
 	tasks.Get("/", adminOnly, s.getAllTasks)
-	tasks.Get("/:id", s.getTask)
-	tasks.Patch("/:id", s.updateTask)
-	tasks.Delete("/:id", s.deleteTask)
-	tasks.Post("/reassign", s.reassignTasks)
+	tasks.Get("/mine", authenticated, s.getMyTasks)
+	tasks.Get("/search", authenticated, s.searchTasks)
+	tasks.Get("/stream", authenticated, s.streamTasks)
+	tasks.Get("/export", adminOnly, s.exportTasks)
+	tasks.Post("/import", adminOnly, s.importTasks)
+	tasks.Get("/:id", authenticated, s.getTask)
+	tasks.Patch("/:id", authenticated, s.updateTask)
+	tasks.Delete("/:id", authenticated, s.deleteTask)
+	tasks.Post("/:id/restore", adminOnly, s.restoreTask)
+	tasks.Post("/reassign", authenticated, s.idempotent, s.reassignTasks)
+	tasks.Post("/reassign/plans/:id/apply", authenticated, s.applyReassignmentPlan)
+	tasks.Get("/:id/history", authenticated, s.getTaskHistory)
+	tasks.Get("/:id/tree", authenticated, s.getTaskTree)
+	tasks.Post("/:id/blockers", authenticated, s.addBlocker)
+	tasks.Delete("/:id/blockers/:blocker_id", authenticated, s.removeBlocker)
+	tasks.Post("/:id/attachments", authenticated, s.createAttachment)
+	tasks.Get("/:id/attachments", authenticated, s.getAttachments)
+	tasks.Get("/:id/attachments/:attachment_id", authenticated, s.getAttachment)
+	tasks.Delete("/:id/attachments/:attachment_id", authenticated, s.deleteAttachment)
+	tasks.Post("/:id/watchers", authenticated, s.watchTask)
+	tasks.Delete("/:id/watchers", authenticated, s.unwatchTask)
+	tasks.Get("/:id/watchers", authenticated, s.getWatchers)
+	tasks.Post("/:id/comments", authenticated, s.createComment)
+	tasks.Get("/:id/comments", authenticated, s.getComments)
+	tasks.Patch("/:id/comments/:comment_id", authenticated, s.updateComment)
+	tasks.Delete("/:id/comments/:comment_id", authenticated, s.deleteComment)
+	tasks.Get("/:id/comments/:comment_id/history", authenticated, s.getCommentHistory)
+
+	labels := base.Group("labels")
+	labels.Post("/", adminOnly, s.createLabel)
+	labels.Get("/", authenticated, s.getLabels)
+	labels.Get("/:id", authenticated, s.getLabel)
+	labels.Patch("/:id", adminOnly, s.updateLabel)
+	labels.Delete("/:id", adminOnly, s.deleteLabel)
+
+	webhooks := base.Group("webhooks")
+	webhooks.Post("/", adminOnly, s.createWebhook)
+	webhooks.Get("/", adminOnly, s.getWebhooks)
+	webhooks.Delete("/:id", adminOnly, s.deleteWebhook)
+	webhooks.Post("/:id/test", adminOnly, s.testWebhook)
+	webhooks.Get("/:id/deliveries", adminOnly, s.getWebhookDeliveries)
+	webhooks.Get("/:id/deliveries/:delivery_id", adminOnly, s.getWebhookDelivery)
+	webhooks.Post("/:id/deliveries/:delivery_id/replay", adminOnly, s.replayWebhookDelivery)
+
+	projects := base.Group("projects")
+	projects.Post("/", adminOnly, s.createProject)
+	projects.Get("/", authenticated, s.getProjects)
+	projects.Get("/:id", authenticated, s.getProject)
+	projects.Patch("/:id", adminOnly, s.updateProject)
+	projects.Post("/:id/members", adminOnly, s.addProjectMembers)
+	projects.Get("/:id/members", authenticated, s.getProjectMembers)
+	projects.Delete("/:id/members/:user_id", adminOnly, s.removeProjectMember)
+	projects.Get("/:id/tasks", authenticated, s.getProjectTasks)
+	projects.Post("/:id/reassign", authenticated, s.idempotent, s.reassignProjectTasks)
+
+	notifications := base.Group("notifications")
+	notifications.Get("/preferences", authenticated, s.getNotificationPreference)
+	notifications.Put("/preferences", authenticated, s.setNotificationPreference)
+
+	assignees := base.Group("assignees")
+	assignees.Patch("/:id", adminOnly, s.updateAssignee)
 }
 
 func parseBody(c *fiber.Ctx, object any) error {
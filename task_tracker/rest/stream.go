@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+	"github.com/ko3luhbka/task_tracker/service"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+
+	streamPollInterval      = time.Second
+	streamHeartbeatInterval = 15 * time.Second
+	// streams are closed after streamMaxDuration, clients reconnect on their
+	// own and resume from the last event they got
+	streamMaxDuration  = 10 * time.Minute
+	streamRetryTimeout = 3 * time.Second
+)
+
+// streamTasks pushes the task feed to the client as Server-Sent Events. The
+// client resumes after the event given in the Last-Event-ID header or the
+// last_event_id query parameter, otherwise only new events are sent. Given
+// the project_id query parameter, the feed of the project is streamed.
+func (s Server) streamTasks(c *fiber.Ctx) error {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	projectID := c.Query("project_id")
+	if projectID != "" {
+		err := s.Svc.CheckProjectAccess(c.Context(), projectID, claims.UUID, claims.Role == adminRole)
+		if errors.Is(err, service.ErrProjectNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+	}
+
+	last, err := lastEventPosition(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if last == nil {
+		last, err = s.Svc.TaskFeedPosition(c.Context())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	userID, isAdmin := claims.UUID, claims.Role == adminRole
+	// the body is written after the handler returns, so the request context
+	// can't be used by then
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx := context.Background()
+		poll := time.NewTicker(streamPollInterval)
+		defer poll.Stop()
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+		deadline := time.NewTimer(streamMaxDuration)
+		defer deadline.Stop()
+
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetryTimeout.Milliseconds())
+		if err := w.Flush(); err != nil {
+			return
+		}
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-deadline.C:
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				if err := w.Flush(); err != nil {
+					return
+				}
+			case <-poll.C:
+				events, err := s.Svc.GetTaskFeed(ctx, *last, projectID, userID, isAdmin)
+				if err != nil {
+					log.Printf("failed to get task feed: %v\n", err)
+					continue
+				}
+				if len(events) == 0 {
+					continue
+				}
+				if err := writeTaskEvents(w, events); err != nil {
+					log.Printf("failed to stream task feed: %v\n", err)
+					return
+				}
+				if last, err = model.ParseTaskFeedPosition(events[len(events)-1].ID); err != nil {
+					log.Printf("failed to resume task feed: %v\n", err)
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// lastEventPosition returns the feed position of the last event the client
+// got or nil if the client starts afresh.
+func lastEventPosition(c *fiber.Ctx) (*db.FeedPosition, error) {
+	id := c.Get(lastEventIDHeader)
+	if id == "" {
+		id = c.Query("last_event_id")
+	}
+	if id == "" {
+		return nil, nil
+	}
+	return model.ParseTaskFeedPosition(id)
+}
+
+func writeTaskEvents(w *bufio.Writer, events []model.TaskFeedEvent) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Kind, data)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+const (
+	RandomStrategy      = "random"
+	RoundRobinStrategy  = "round_robin"
+	LeastLoadedStrategy = "least_loaded"
+	WeightedStrategy    = "weighted"
+)
+
+type (
+	// AssignmentStrategy picks the worker a task is (re)assigned to. workers
+	// is never empty and is sorted by ID.
+	AssignmentStrategy interface {
+		Pick(workers []db.Worker) *db.Worker
+	}
+	randomStrategy      struct{}
+	leastLoadedStrategy struct{}
+	weightedStrategy    struct{}
+	roundRobinStrategy  struct {
+		mu     sync.Mutex
+		lastID string
+	}
+)
+
+func NewAssignmentStrategy(name string) (AssignmentStrategy, error) {
+	rand.Seed(time.Now().UnixNano())
+
+	switch name {
+	case RandomStrategy:
+		return randomStrategy{}, nil
+	case RoundRobinStrategy:
+		return &roundRobinStrategy{}, nil
+	case LeastLoadedStrategy:
+		return leastLoadedStrategy{}, nil
+	case WeightedStrategy:
+		return weightedStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown assignment strategy: %s", name)
+	}
+}
+
+func (randomStrategy) Pick(workers []db.Worker) *db.Worker {
+	return &workers[rand.Intn(len(workers))]
+}
+
+// Pick returns the worker following the previously picked one, so the
+// rotation survives workers joining or leaving between picks.
+func (s *roundRobinStrategy) Pick(workers []db.Worker) *db.Worker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := &workers[0]
+	for i := range workers {
+		if workers[i].ID > s.lastID {
+			next = &workers[i]
+			break
+		}
+	}
+	s.lastID = next.ID
+	return next
+}
+
+// Pick returns the worker with the fewest open tasks, ties are broken randomly.
+func (leastLoadedStrategy) Pick(workers []db.Worker) *db.Worker {
+	var candidates []*db.Worker
+	for i := range workers {
+		w := &workers[i]
+		switch {
+		case len(candidates) == 0 || w.OpenTasks < candidates[0].OpenTasks:
+			candidates = []*db.Worker{w}
+		case w.OpenTasks == candidates[0].OpenTasks:
+			candidates = append(candidates, w)
+		}
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// Pick returns a random worker with the probability proportional to its
+// capacity. Workers with zero capacity are never picked unless all of them are.
+func (weightedStrategy) Pick(workers []db.Worker) *db.Worker {
+	total := 0
+	for _, w := range workers {
+		total += w.Capacity
+	}
+	if total <= 0 {
+		return randomStrategy{}.Pick(workers)
+	}
+
+	n := rand.Intn(total)
+	for i := range workers {
+		if n < workers[i].Capacity {
+			return &workers[i]
+		}
+		n -= workers[i].Capacity
+	}
+	return &workers[len(workers)-1]
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+// picks is how many times a randomized function is run, so that any result
+// it must never produce would likely show up
+const picks = 100
+
+func newWorker(id string, openTasks, capacity int) db.Worker {
+	return db.Worker{
+		Assignee:  db.Assignee{ID: id, Capacity: capacity},
+		OpenTasks: openTasks,
+	}
+}
+
+func TestNewAssignmentStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: RandomStrategy},
+		{name: RoundRobinStrategy},
+		{name: LeastLoadedStrategy},
+		{name: WeightedStrategy},
+		{name: "fastest", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewAssignmentStrategy(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAssignmentStrategy() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && s == nil {
+				t.Error("NewAssignmentStrategy() returned no strategy")
+			}
+		})
+	}
+}
+
+func TestRoundRobinStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers [][]db.Worker
+		want    []string
+	}{
+		{
+			name: "rotation",
+			workers: [][]db.Worker{
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+			},
+			want: []string{"a", "b", "c", "a"},
+		},
+		{
+			name: "picked worker leaves",
+			workers: [][]db.Worker{
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("c", 0, 0)},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "worker joins",
+			workers: [][]db.Worker{
+				{newWorker("a", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+				{newWorker("a", 0, 0), newWorker("b", 0, 0), newWorker("c", 0, 0)},
+			},
+			want: []string{"a", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &roundRobinStrategy{}
+			for i, workers := range tt.workers {
+				if got := s.Pick(workers).ID; got != tt.want[i] {
+					t.Errorf("pick %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLeastLoadedStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []db.Worker
+		want    map[string]bool
+	}{
+		{
+			name:    "single least loaded",
+			workers: []db.Worker{newWorker("a", 3, 0), newWorker("b", 1, 0), newWorker("c", 2, 0)},
+			want:    map[string]bool{"b": true},
+		},
+		{
+			name:    "tie",
+			workers: []db.Worker{newWorker("a", 1, 0), newWorker("b", 4, 0), newWorker("c", 1, 0)},
+			want:    map[string]bool{"a": true, "c": true},
+		},
+		{
+			name:    "single worker",
+			workers: []db.Worker{newWorker("a", 7, 0)},
+			want:    map[string]bool{"a": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < picks; i++ {
+				if got := (leastLoadedStrategy{}).Pick(tt.workers).ID; !tt.want[got] {
+					t.Fatalf("Pick() = %s, want one of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []db.Worker
+		want    map[string]bool
+	}{
+		{
+			name:    "zero capacity is never picked",
+			workers: []db.Worker{newWorker("a", 0, 0), newWorker("b", 0, 3), newWorker("c", 0, 0)},
+			want:    map[string]bool{"b": true},
+		},
+		{
+			name:    "all with capacity",
+			workers: []db.Worker{newWorker("a", 0, 1), newWorker("b", 0, 2)},
+			want:    map[string]bool{"a": true, "b": true},
+		},
+		{
+			name:    "all with zero capacity",
+			workers: []db.Worker{newWorker("a", 0, 0), newWorker("b", 0, 0)},
+			want:    map[string]bool{"a": true, "b": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < picks; i++ {
+				if got := (weightedStrategy{}).Pick(tt.workers).ID; !tt.want[got] {
+					t.Fatalf("Pick() = %s, want one of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
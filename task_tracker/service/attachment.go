@@ -0,0 +1,200 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+	"github.com/ko3luhbka/task_tracker/storage"
+)
+
+// sniffLength is how many bytes the content type is detected from.
+const sniffLength = 512
+
+var (
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentTooLarge   = errors.New("attachment is too large")
+	ErrUnsupportedMediaType = errors.New("attachment content type is not allowed")
+)
+
+// CreateAttachment stores the content read from r and attaches it to the task.
+// The content type is sniffed from the content, the size and the checksum
+// are taken while it's being stored.
+func (s Service) CreateAttachment(ctx context.Context, a model.Attachment, r io.Reader) (*model.Attachment, error) {
+	task, err := s.taskRepo.GetByID(ctx, a.TaskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// deleted tasks keep their attachments but don't get new ones
+	if task.DeletedAt != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	br := bufio.NewReaderSize(r, sniffLength)
+	head, err := br.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	a.ContentType = attachmentContentType(head, a.ContentType)
+	if !model.IsAllowedAttachmentType(a.ContentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, a.ContentType)
+	}
+
+	key, err := newAttachmentKey(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	counter := &countingWriter{}
+	// read one byte past the limit to tell a file of exactly the limit size
+	// from a larger one
+	content := io.TeeReader(io.LimitReader(br, model.MaxAttachmentSize+1), io.MultiWriter(hash, counter))
+	if err := s.blobs.Put(ctx, key, content); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %v", err)
+	}
+	if counter.n > model.MaxAttachmentSize {
+		s.deleteBlob(ctx, key)
+		return nil, fmt.Errorf("%w: the limit is %d bytes", ErrAttachmentTooLarge, model.MaxAttachmentSize)
+	}
+
+	e := a.ToEntity()
+	e.Size = counter.n
+	e.Checksum = hex.EncodeToString(hash.Sum(nil))
+	e.StorageKey = key
+	created, err := s.attachmentRepo.Create(ctx, *e)
+	if err != nil {
+		s.deleteBlob(ctx, key)
+		return nil, err
+	}
+
+	m := new(model.Attachment)
+	m.FromEntity(created)
+	return m, nil
+}
+
+func (s Service) GetAttachments(ctx context.Context, taskID string) ([]model.Attachment, error) {
+	if _, err := s.taskRepo.GetByID(ctx, taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	attachments, err := s.attachmentRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	attachmentsModel := make([]model.Attachment, len(attachments))
+	for i, a := range attachments {
+		attachmentsModel[i].FromEntity(&a)
+	}
+	return attachmentsModel, nil
+}
+
+// GetAttachment returns the attachment along with its content, which the
+// caller must close.
+func (s Service) GetAttachment(ctx context.Context, taskID, uuid string) (*model.Attachment, io.ReadCloser, error) {
+	a, err := s.getAttachment(ctx, taskID, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.blobs.Get(ctx, a.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("content of attachment %s is missing\n", a.ID)
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := new(model.Attachment)
+	m.FromEntity(a)
+	return m, content, nil
+}
+
+// DeleteAttachment removes the attachment. Only the uploader or an admin may
+// delete an attachment.
+func (s Service) DeleteAttachment(ctx context.Context, taskID, uuid, actorID string, isAdmin bool) error {
+	a, err := s.getAttachment(ctx, taskID, uuid)
+	if err != nil {
+		return err
+	}
+	if a.UploaderID != actorID && !isAdmin {
+		return ErrForbidden
+	}
+
+	if err := s.attachmentRepo.Delete(ctx, a.ID); err != nil {
+		return err
+	}
+	s.deleteBlob(ctx, a.StorageKey)
+	return nil
+}
+
+// getAttachment returns the attachment of a task which is not deleted.
+func (s Service) getAttachment(ctx context.Context, taskID, uuid string) (*db.Attachment, error) {
+	if _, err := s.taskRepo.GetByID(ctx, taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	a, err := s.attachmentRepo.GetByID(ctx, taskID, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// deleteBlob removes the content of an attachment. Failures are only logged:
+// the content is unreachable once its metadata is gone anyway.
+func (s Service) deleteBlob(ctx context.Context, key string) {
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete attachment content %s: %v\n", key, err)
+	}
+}
+
+// attachmentContentType detects the content type from the head of the
+// content. The declared type is only trusted for JSON, which is detected as
+// plain text.
+func attachmentContentType(head []byte, declared string) string {
+	detected := http.DetectContentType(head)
+	if strings.HasPrefix(detected, "text/plain") && strings.HasPrefix(declared, "application/json") {
+		return "application/json"
+	}
+	return detected
+}
+
+func newAttachmentKey(taskID string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate attachment key: %v", err)
+	}
+	return "tasks/" + taskID + "/" + hex.EncodeToString(b), nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
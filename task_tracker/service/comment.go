@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/mq"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	commentSchemaType    = "task_comment"
+	commentSchemaVersion = 1
+)
+
+var (
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrForbidden       = errors.New("not allowed to access the resource")
+)
+
+func (s Service) CreateComment(ctx context.Context, c model.Comment) (*model.Comment, error) {
+	m := new(model.Comment)
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := checkTaskNotDeleted(ctx, s.taskRepo.WithTx(tx), c.TaskID); err != nil {
+			return err
+		}
+
+		created, err := s.commentRepo.WithTx(tx).Create(ctx, *c.ToEntity())
+		if err != nil {
+			return err
+		}
+		m.FromEntity(created)
+
+		e := mq.CommentEvent{
+			Name:    mq.TaskCommentedEvent,
+			Version: commentSchemaVersion,
+			Data:    *m,
+		}
+		return s.enqueueEvent(ctx, tx, e.Data.TaskID, e.Name, e, commentSchemaType, e.Version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s Service) GetComments(ctx context.Context, taskID string) ([]model.Comment, error) {
+	if err := s.checkTaskExists(ctx, taskID); err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	commentsModel := make([]model.Comment, len(comments))
+	for i, c := range comments {
+		m := new(model.Comment)
+		m.FromEntity(&c)
+		commentsModel[i] = *m
+	}
+	return commentsModel, nil
+}
+
+// UpdateComment replaces the comment body keeping the previous one in the
+// edit history. Only the author may edit a comment.
+func (s Service) UpdateComment(ctx context.Context, c model.Comment, editorID string) (*model.Comment, error) {
+	m := new(model.Comment)
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := checkTaskNotDeleted(ctx, s.taskRepo.WithTx(tx), c.TaskID); err != nil {
+			return err
+		}
+		commentRepo := s.commentRepo.WithTx(tx)
+		current, err := commentRepo.GetByIDForUpdate(ctx, c.TaskID, c.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.AuthorID != editorID {
+			return ErrForbidden
+		}
+
+		edit := db.CommentEdit{
+			CommentID: current.ID,
+			Body:      current.Body,
+			EditorID:  editorID,
+		}
+		if err := commentRepo.CreateEdit(ctx, edit); err != nil {
+			return err
+		}
+		updated, err := commentRepo.UpdateBody(ctx, c.ID, c.Body)
+		if err != nil {
+			return err
+		}
+		m.FromEntity(updated)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DeleteComment deletes the comment along with its edit history. Only the
+// author or an admin may delete a comment.
+func (s Service) DeleteComment(ctx context.Context, taskID, uuid, actorID string, isAdmin bool) error {
+	return s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		commentRepo := s.commentRepo.WithTx(tx)
+		current, err := commentRepo.GetByIDForUpdate(ctx, taskID, uuid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.AuthorID != actorID && !isAdmin {
+			return ErrForbidden
+		}
+		return commentRepo.Delete(ctx, uuid)
+	})
+}
+
+func (s Service) GetCommentEdits(ctx context.Context, taskID, commentID string) ([]model.CommentEdit, error) {
+	if _, err := s.commentRepo.GetByID(ctx, taskID, commentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	edits, err := s.commentRepo.GetEdits(ctx, taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	editsModel := make([]model.CommentEdit, len(edits))
+	for i, e := range edits {
+		m := new(model.CommentEdit)
+		m.FromEntity(&e)
+		editsModel[i] = *m
+	}
+	return editsModel, nil
+}
+
+// checkTaskNotDeleted returns ErrTaskNotFound unless the task exists and is not
+// deleted. Deleted tasks keep their comments but don't get new ones.
+func checkTaskNotDeleted(ctx context.Context, taskRepo *db.TaskRepo, taskID string) error {
+	task, err := taskRepo.GetByID(ctx, taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if task.DeletedAt != nil {
+		return ErrTaskNotFound
+	}
+	return nil
+}
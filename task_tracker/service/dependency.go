@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+var (
+	ErrParentNotFound   = errors.New("parent task not found")
+	ErrDependencyCycle  = errors.New("task dependencies can't make up a cycle")
+	ErrOpenDependencies = errors.New("task has uncompleted blockers or subtasks")
+	ErrLinkNotFound     = errors.New("task link not found")
+)
+
+// LinkTasks makes the blocker task block the other one.
+func (s Service) LinkTasks(ctx context.Context, l model.TaskLink) (*model.TaskLink, error) {
+	m := new(model.TaskLink)
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		linkRepo := s.linkRepo.WithTx(tx)
+		if err := linkRepo.Lock(ctx); err != nil {
+			return err
+		}
+
+		tasks, err := s.taskRepo.WithTx(tx).GetByIDs(ctx, []string{l.BlockerID, l.BlockedID})
+		if err != nil {
+			return err
+		}
+		if len(tasks) != 2 {
+			return ErrTaskNotFound
+		}
+
+		cycle, err := linkRepo.IsBlocking(ctx, l.BlockedID, l.BlockerID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("%w: task %s already blocks %s", ErrDependencyCycle, l.BlockedID, l.BlockerID)
+		}
+
+		created, err := linkRepo.Create(ctx, *l.ToEntity())
+		if err != nil {
+			return err
+		}
+		m.FromEntity(created)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s Service) UnlinkTasks(ctx context.Context, blockerID, blockedID string) error {
+	err := s.linkRepo.Delete(ctx, blockerID, blockedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: task %s doesn't block task %s", ErrLinkNotFound, blockerID, blockedID)
+	}
+	return err
+}
+
+// GetTaskTree returns the task with all of its subtasks, each of them along
+// with the tasks blocking it and blocked by it.
+func (s Service) GetTaskTree(ctx context.Context, uuid string) (*model.TaskTree, error) {
+	subtree, err := s.taskRepo.GetSubtree(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if len(subtree) == 0 {
+		return nil, ErrTaskNotFound
+	}
+
+	ids := make([]string, len(subtree))
+	for i, t := range subtree {
+		ids[i] = t.ID
+	}
+	links, err := s.linkRepo.GetForTasks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	linkedIDs := make([]string, 0, 2*len(links))
+	for _, l := range links {
+		linkedIDs = append(linkedIDs, l.BlockerID, l.BlockedID)
+	}
+	linked, err := s.taskRepo.GetByIDs(ctx, linkedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewTaskTree(uuid, subtree, links, linked), nil
+}
+
+// checkParent makes sure the parent task exists and the task isn't one of
+// the parent's ancestors.
+func (s Service) checkParent(ctx context.Context, tx *sqlx.Tx, taskID, parentID string) error {
+	if err := s.linkRepo.WithTx(tx).Lock(ctx); err != nil {
+		return err
+	}
+
+	taskRepo := s.taskRepo.WithTx(tx)
+	_, err := taskRepo.GetByID(ctx, parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrParentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if taskID == "" {
+		return nil
+	}
+
+	if taskID == parentID {
+		return fmt.Errorf("%w: task can't be its own parent", ErrDependencyCycle)
+	}
+	cycle, err := taskRepo.IsAncestor(ctx, taskID, parentID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return fmt.Errorf("%w: task %s is a subtask of %s", ErrDependencyCycle, parentID, taskID)
+	}
+	return nil
+}
+
+// checkCanComplete returns ErrOpenDependencies if the task has uncompleted
+// blockers or subtasks.
+func (s Service) checkCanComplete(ctx context.Context, tx *sqlx.Tx, taskID string) error {
+	blockers, err := s.linkRepo.WithTx(tx).CountOpenBlockers(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	subtasks, err := s.taskRepo.WithTx(tx).CountOpenSubtasks(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if blockers > 0 || subtasks > 0 {
+		return fmt.Errorf("%w: %d blockers, %d subtasks", ErrOpenDependencies, blockers, subtasks)
+	}
+	return nil
+}
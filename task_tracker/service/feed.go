@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	taskFeedBatchSize = 100
+	// taskFeedRetention is how far back a feed client can resume from
+	taskFeedRetention = 24 * time.Hour
+)
+
+// TaskFeedPosition returns the position a client following the task feed
+// from now on starts after.
+func (s Service) TaskFeedPosition(ctx context.Context) (*db.FeedPosition, error) {
+	return s.feedRepo.Position(ctx)
+}
+
+// GetTaskFeed returns the task feed events following the given position.
+// Non admins only get the events of the tasks they are or used to be assigned
+// to. The feed of a project consists of the events of the project tasks, all
+// of which are visible to the project members. Whether the user may follow
+// the project feed is checked by CheckProjectAccess beforehand.
+func (s Service) GetTaskFeed(ctx context.Context, after db.FeedPosition, projectID, userID string, isAdmin bool) ([]model.TaskFeedEvent, error) {
+	assigneeID := userID
+	if isAdmin || projectID != "" {
+		assigneeID = ""
+	}
+	entries, err := s.feedRepo.GetAfter(ctx, after, assigneeID, projectID, taskFeedBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.TaskFeedEvent, len(entries))
+	for i, e := range entries {
+		events[i].FromEntity(&e)
+	}
+	return events, nil
+}
+
+// addToFeed publishes the task change to the feed within tx. prev is nil for
+// a newly created task.
+func (s Service) addToFeed(ctx context.Context, tx *sqlx.Tx, prev, cur *db.Task) error {
+	payload, err := json.Marshal(model.TaskEntityToTaskInfo(cur))
+	if err != nil {
+		return fmt.Errorf("failed to marshal task feed event: %v", err)
+	}
+
+	e := db.TaskFeedEntry{
+		Kind:       taskFeedKind(prev, cur),
+		TaskID:     cur.ID,
+		AssigneeID: cur.AssigneeID,
+		ProjectID:  cur.ProjectID,
+		Payload:    payload,
+	}
+	if prev != nil && prev.AssigneeID != cur.AssigneeID {
+		e.PreviousAssigneeID = prev.AssigneeID
+	}
+	return s.feedRepo.WithTx(tx).Create(ctx, e)
+}
+
+func taskFeedKind(prev, cur *db.Task) string {
+	switch {
+	case prev == nil:
+		return model.TaskFeedCreated
+	case prev.DeletedAt == nil && cur.DeletedAt != nil:
+		return model.TaskFeedDeleted
+	case prev.DeletedAt != nil && cur.DeletedAt == nil:
+		return model.TaskFeedRestored
+	case prev.AssigneeID != cur.AssigneeID:
+		return model.TaskFeedReassigned
+	default:
+		return model.TaskFeedUpdated
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+// GetTaskHistory returns the changes of the task fields, oldest first.
+func (s Service) GetTaskHistory(ctx context.Context, taskID string) ([]model.TaskChange, error) {
+	_, err := s.taskRepo.GetByID(ctx, taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.historyRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	changesModel := make([]model.TaskChange, len(changes))
+	for i, c := range changes {
+		m := new(model.TaskChange)
+		m.FromEntity(&c)
+		changesModel[i] = *m
+	}
+	return changesModel, nil
+}
+
+// recordChanges stores every field that differs between prev and cur within tx,
+// publishes the change to the task feed and notifies the watchers of it.
+func (s Service) recordChanges(ctx context.Context, tx *sqlx.Tx, prev, cur *db.Task, actorID string) error {
+	changes := taskChanges(prev, cur, actorID)
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := s.historyRepo.WithTx(tx).Create(ctx, changes); err != nil {
+		return err
+	}
+	if err := s.addToFeed(ctx, tx, prev, cur); err != nil {
+		return err
+	}
+
+	fields := make([]string, len(changes))
+	for i, c := range changes {
+		fields[i] = c.Field
+	}
+	return s.notifyTaskChanged(ctx, tx, prev, cur, actorID, fields)
+}
+
+func taskChanges(prev, cur *db.Task, actorID string) []db.TaskChange {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"title", prev.Title, cur.Title},
+		{"jira_id", prev.JiraID, cur.JiraID},
+		{"description", prev.Description, cur.Description},
+		{"status", prev.Status, cur.Status},
+		{"assignee_id", prev.AssigneeID, cur.AssigneeID},
+		{"parent_id", prev.ParentID, cur.ParentID},
+		{"labels", strings.Join(prev.Labels, ","), strings.Join(cur.Labels, ",")},
+		{"due_date", formatTime(prev.DueDate), formatTime(cur.DueDate)},
+		{"priority", prev.Priority, cur.Priority},
+		{"estimate", strconv.Itoa(prev.Estimate), strconv.Itoa(cur.Estimate)},
+		{"deleted_at", formatTime(prev.DeletedAt), formatTime(cur.DeletedAt)},
+	}
+
+	var changes []db.TaskChange
+	for _, f := range fields {
+		if f.old == f.new {
+			continue
+		}
+		changes = append(changes, db.TaskChange{
+			TaskID:   cur.ID,
+			Field:    f.name,
+			OldValue: f.old,
+			NewValue: f.new,
+			ActorID:  actorID,
+		})
+	}
+	return changes
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	// idempotencyKeyTTL is how long responses are replayed for the same key.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyKeyLease is how long the first request with a key is waited
+	// for. Once it's over with no response saved, e.g. the service has died
+	// handling the request, a retry handles the request instead.
+	idempotencyKeyLease = time.Minute
+)
+
+var (
+	ErrIdempotencyKeyReused = errors.New("idempotency key has been used for a different request")
+	ErrRequestInProgress    = errors.New("request with the same idempotency key is in progress")
+)
+
+// BeginIdempotentRequest registers the request made with the key to endpoint.
+// If the key has been used for the same request within idempotencyKeyTTL,
+// the response to that request is returned, otherwise nil is returned and the
+// request is to be handled and finished with FinishIdempotentRequest.
+func (s Service) BeginIdempotentRequest(ctx context.Context, key, endpoint string, request []byte) (*model.IdempotentResponse, error) {
+	hash := sha256.Sum256(request)
+	k := db.IdempotencyKey{
+		Key:         key,
+		Endpoint:    endpoint,
+		RequestHash: hex.EncodeToString(hash[:]),
+	}
+
+	for {
+		reserved, err := s.idempotencyRepo.Reserve(ctx, k, idempotencyKeyTTL, idempotencyKeyLease)
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		stored, err := s.idempotencyRepo.Get(ctx, key, endpoint)
+		if errors.Is(err, sql.ErrNoRows) {
+			// the first request has failed and released the key in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stored.RequestHash != k.RequestHash {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, key)
+		}
+		if stored.StatusCode == nil {
+			return nil, fmt.Errorf("%w: %s", ErrRequestInProgress, key)
+		}
+
+		m := new(model.IdempotentResponse)
+		m.FromEntity(stored)
+		return m, nil
+	}
+}
+
+// FinishIdempotentRequest stores the response to be replayed for the retries
+// of the request. Server errors are not stored, so that the request can be
+// retried with the same key.
+func (s Service) FinishIdempotentRequest(ctx context.Context, key, endpoint string, resp model.IdempotentResponse) error {
+	if resp.StatusCode >= 500 {
+		return s.idempotencyRepo.Delete(ctx, key, endpoint)
+	}
+	return s.idempotencyRepo.SaveResponse(ctx, db.IdempotencyKey{
+		Key:         key,
+		Endpoint:    endpoint,
+		StatusCode:  &resp.StatusCode,
+		ContentType: &resp.ContentType,
+		Response:    resp.Body,
+	})
+}
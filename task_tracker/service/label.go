@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+var (
+	ErrLabelNotFound = errors.New("label not found")
+	ErrLabelExists   = errors.New("label already exists")
+	ErrUnknownLabel  = errors.New("unknown label")
+)
+
+func (s Service) CreateLabel(ctx context.Context, l model.Label) (*model.Label, error) {
+	existing, err := s.labelRepo.GetByNames(ctx, []string{l.Name})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrLabelExists, l.Name)
+	}
+
+	created, err := s.labelRepo.Create(ctx, *l.ToEntity())
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Label)
+	m.FromEntity(created)
+	return m, nil
+}
+
+func (s Service) GetLabels(ctx context.Context) ([]model.Label, error) {
+	labels, err := s.labelRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labelsModel := make([]model.Label, len(labels))
+	for i, l := range labels {
+		m := new(model.Label)
+		m.FromEntity(&l)
+		labelsModel[i] = *m
+	}
+	return labelsModel, nil
+}
+
+func (s Service) GetLabelByID(ctx context.Context, uuid string) (*model.Label, error) {
+	label, err := s.labelRepo.GetByID(ctx, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLabelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Label)
+	m.FromEntity(label)
+	return m, nil
+}
+
+func (s Service) UpdateLabel(ctx context.Context, l model.Label) (*model.Label, error) {
+	if l.Name != "" {
+		existing, err := s.labelRepo.GetByNames(ctx, []string{l.Name})
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) != 0 && existing[0].ID != l.ID {
+			return nil, fmt.Errorf("%w: %s", ErrLabelExists, l.Name)
+		}
+	}
+
+	updated, err := s.labelRepo.Update(ctx, *l.ToEntity())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLabelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Label)
+	m.FromEntity(updated)
+	return m, nil
+}
+
+// DeleteLabel deletes the label and removes it from all the tasks.
+func (s Service) DeleteLabel(ctx context.Context, uuid string) error {
+	return s.labelRepo.Delete(ctx, uuid)
+}
+
+// setTaskLabels replaces the labels of the task with the ones named within tx.
+// ErrUnknownLabel is returned if some of the names are not in the catalog.
+func (s Service) setTaskLabels(ctx context.Context, tx *sqlx.Tx, task *db.Task, names []string) error {
+	labelRepo := s.labelRepo.WithTx(tx)
+	labels, err := labelRepo.GetByNames(ctx, names)
+	if err != nil {
+		return err
+	}
+	if err := checkLabelsFound(names, labels); err != nil {
+		return err
+	}
+
+	if err := labelRepo.SetTaskLabels(ctx, task.ID, labels); err != nil {
+		return err
+	}
+	task.Labels = make([]string, len(labels))
+	for i, l := range labels {
+		task.Labels[i] = l.Name
+	}
+	sort.Strings(task.Labels)
+	return nil
+}
+
+func checkLabelsFound(names []string, labels []db.Label) error {
+	found := make(map[string]bool, len(labels))
+	for _, l := range labels {
+		found[l.Name] = true
+	}
+	var unknown []string
+	for _, n := range names {
+		if !found[n] {
+			unknown = append(unknown, n)
+		}
+	}
+	if len(unknown) != 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownLabel, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// fillTaskLabels sets the labels of all the tasks with a single query.
+func fillTaskLabels(ctx context.Context, labelRepo *db.LabelRepo, tasks []db.Task) error {
+	ptrs := make([]*db.Task, len(tasks))
+	for i := range tasks {
+		ptrs[i] = &tasks[i]
+	}
+	return labelRepo.FillTaskLabels(ctx, ptrs...)
+}
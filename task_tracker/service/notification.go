@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/mq"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	notificationSchemaType    = "notification"
+	notificationSchemaVersion = 1
+)
+
+func (s Service) WatchTask(ctx context.Context, taskID, userID string) error {
+	if err := s.checkTaskExists(ctx, taskID); err != nil {
+		return err
+	}
+	return s.watcherRepo.Add(ctx, taskID, userID)
+}
+
+func (s Service) UnwatchTask(ctx context.Context, taskID, userID string) error {
+	if err := s.checkTaskExists(ctx, taskID); err != nil {
+		return err
+	}
+	return s.watcherRepo.Remove(ctx, taskID, userID)
+}
+
+func (s Service) GetWatchers(ctx context.Context, taskID string) ([]model.Watcher, error) {
+	if err := s.checkTaskExists(ctx, taskID); err != nil {
+		return nil, err
+	}
+	watchers, err := s.watcherRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	watchersModel := make([]model.Watcher, len(watchers))
+	for i, w := range watchers {
+		watchersModel[i].FromEntity(&w)
+	}
+	return watchersModel, nil
+}
+
+// GetNotificationPreference returns how the user wants to be notified. Users
+// who haven't chosen are notified by email.
+func (s Service) GetNotificationPreference(ctx context.Context, userID string) (*model.NotificationPreference, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model.NotificationPreference{
+		UserID: userID,
+		Mode:   model.NotifyByEmail,
+	}
+	if len(prefs) != 0 {
+		m.FromEntity(&prefs[0])
+	}
+	return m, nil
+}
+
+func (s Service) SetNotificationPreference(ctx context.Context, p model.NotificationPreference) (*model.NotificationPreference, error) {
+	updated, err := s.notificationRepo.SetPreference(ctx, db.NotificationPreference{
+		UserID: p.UserID,
+		Mode:   p.Mode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.NotificationPreference)
+	m.FromEntity(updated)
+	return m, nil
+}
+
+// notifyTaskChanged makes the new assignee of the task watch it and enqueues
+// the notifications about the change within tx: taskAssignedToYou for the new
+// assignee and watchedTaskChanged for the rest of the watchers. prev is nil
+// for a newly created task, fields are the names of the changed fields. The
+// actor is never notified of its own changes.
+func (s Service) notifyTaskChanged(ctx context.Context, tx *sqlx.Tx, prev, cur *db.Task, actorID string, fields []string) error {
+	watcherRepo := s.watcherRepo.WithTx(tx)
+	assigned := prev == nil || prev.AssigneeID != cur.AssigneeID
+	if assigned {
+		if err := watcherRepo.Add(ctx, cur.ID, cur.AssigneeID); err != nil {
+			return err
+		}
+	}
+
+	change := taskFeedKind(prev, cur)
+	var notifications []mq.NotificationEvent
+	notify := func(name, recipientID string) {
+		if recipientID == actorID {
+			return
+		}
+		notifications = append(notifications, mq.NotificationEvent{
+			Name:    name,
+			Version: notificationSchemaVersion,
+			Data: model.Notification{
+				RecipientID: recipientID,
+				Task:        *model.TaskEntityToTaskRef(cur),
+				Change:      change,
+				Fields:      fields,
+				ActorID:     actorID,
+			},
+		})
+	}
+
+	if assigned {
+		notify(mq.TaskAssignedToYouEvent, cur.AssigneeID)
+	}
+	if prev != nil {
+		watchers, err := watcherRepo.GetByTask(ctx, cur.ID)
+		if err != nil {
+			return err
+		}
+		for _, w := range watchers {
+			if !assigned || w.UserID != cur.AssigneeID {
+				notify(mq.WatchedTaskChangedEvent, w.UserID)
+			}
+		}
+	}
+	return s.enqueueNotifications(ctx, tx, notifications)
+}
+
+// enqueueNotifications sets the delivery of the notifications according to
+// the preferences of their recipients and enqueues them within tx. The
+// notifications of the recipients who turned them off are dropped.
+func (s Service) enqueueNotifications(ctx context.Context, tx *sqlx.Tx, notifications []mq.NotificationEvent) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	recipients := make([]string, len(notifications))
+	for i, n := range notifications {
+		recipients[i] = n.Data.RecipientID
+	}
+	prefs, err := s.notificationRepo.WithTx(tx).GetPreferences(ctx, recipients)
+	if err != nil {
+		return err
+	}
+	modes := make(map[string]string, len(prefs))
+	for _, p := range prefs {
+		modes[p.UserID] = p.Mode
+	}
+
+	for _, n := range notifications {
+		mode, ok := modes[n.Data.RecipientID]
+		if !ok {
+			mode = model.NotifyByEmail
+		}
+		if mode == model.NotifyOff {
+			continue
+		}
+		n.Data.Delivery = mode
+		if _, err := s.enqueueEventTo(ctx, tx, mq.NotificationsTopic, n.Data.RecipientID, n.Name, n, notificationSchemaType, n.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Service) checkTaskExists(ctx context.Context, taskID string) error {
+	_, err := s.taskRepo.GetByID(ctx, taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/mq"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	overdueCheckInterval = time.Minute
+	overdueBatchSize     = 100
+)
+
+// RunOverdueChecker periodically looks for tasks which have got overdue since
+// the previous check and publishes a taskOverdue event for each of them until
+// done is closed. Every task is reported once per due date.
+func (s Service) RunOverdueChecker(ctx context.Context, done chan bool) {
+	ticker := time.NewTicker(overdueCheckInterval)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case tick := <-ticker.C:
+				if err := s.reportOverdueTasks(ctx, tick); err != nil {
+					log.Printf("failed to report overdue tasks: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s Service) reportOverdueTasks(ctx context.Context, now time.Time) error {
+	for {
+		var reported int
+		err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+			taskRepo := s.taskRepo.WithTx(tx)
+			tasks, err := taskRepo.LockNewlyOverdue(ctx, now, overdueBatchSize)
+			if err != nil {
+				return err
+			}
+			if err := fillTaskLabels(ctx, s.labelRepo.WithTx(tx), tasks); err != nil {
+				return err
+			}
+
+			events := make([]mq.TaskEvent, len(tasks))
+			for i, task := range tasks {
+				if err := taskRepo.MarkOverdueNotified(ctx, task.ID); err != nil {
+					return err
+				}
+				events[i] = mq.TaskEvent{
+					Name:    mq.TaskOverdueEvent,
+					Version: taskSchemaVersion,
+					Data:    *model.TaskEntityToTaskInfo(&task),
+				}
+			}
+			reported = len(tasks)
+			return s.enqueueTaskEvents(ctx, tx, events...)
+		})
+		if err != nil {
+			return err
+		}
+		if reported > 0 {
+			log.Printf("%d tasks are overdue\n", reported)
+		}
+		if reported < overdueBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	minAssignFee    = 10
+	maxAssignFee    = 20
+	minCompleteCost = 20
+	maxCompleteCost = 40
+
+	// rewardPerStoryPoint is added to the completion reward for every story
+	// point the task is estimated at
+	rewardPerStoryPoint = 5
+)
+
+// priorityPriceRates are the percentages both prices of a task are scaled by
+// according to its priority.
+var priorityPriceRates = map[string]int{
+	model.TaskPriorityCritical: 200,
+	model.TaskPriorityHigh:     150,
+	model.TaskPriorityNormal:   100,
+	model.TaskPriorityLow:      75,
+}
+
+// priceTask sets the assign fee and the completion reward of a new task.
+// Prices are calculated only once, so a reassigned task always costs the same,
+// even if its priority or estimate are changed later.
+func priceTask(t *model.Task) {
+	rate, ok := priorityPriceRates[t.Priority]
+	if !ok {
+		rate = priorityPriceRates[model.TaskPriorityNormal]
+	}
+	reward := getRandNumInRange(minCompleteCost, maxCompleteCost) + t.Estimate*rewardPerStoryPoint
+
+	t.AssignFee = getRandNumInRange(minAssignFee, maxAssignFee) * rate / 100
+	t.CompleteCost = reward * rate / 100
+}
+
+func getRandNumInRange(min, max int) int {
+	rand.Seed(time.Now().UnixNano())
+	return rand.Intn(max-min) + min
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+func TestPriceTask(t *testing.T) {
+	// prices are rolled, so they are only checked to be within the bounds
+	tests := []struct {
+		name     string
+		priority string
+		estimate int
+		minFee   int
+		maxFee   int
+		minCost  int
+		maxCost  int
+	}{
+		{
+			name:     "critical",
+			priority: model.TaskPriorityCritical,
+			minFee:   20,
+			maxFee:   38,
+			minCost:  40,
+			maxCost:  78,
+		},
+		{
+			name:     "high with estimate",
+			priority: model.TaskPriorityHigh,
+			estimate: 3,
+			minFee:   15,
+			maxFee:   28,
+			minCost:  52,
+			maxCost:  81,
+		},
+		{
+			name:     "normal with estimate",
+			priority: model.TaskPriorityNormal,
+			estimate: 5,
+			minFee:   10,
+			maxFee:   19,
+			minCost:  45,
+			maxCost:  64,
+		},
+		{
+			name:     "low with estimate",
+			priority: model.TaskPriorityLow,
+			estimate: 1,
+			minFee:   7,
+			maxFee:   14,
+			minCost:  18,
+			maxCost:  33,
+		},
+		{
+			name:     "unknown priority is priced as normal",
+			priority: "P9",
+			minFee:   10,
+			maxFee:   19,
+			minCost:  20,
+			maxCost:  39,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < picks; i++ {
+				task := model.Task{Priority: tt.priority, Estimate: tt.estimate}
+				priceTask(&task)
+				if task.AssignFee < tt.minFee || task.AssignFee > tt.maxFee {
+					t.Fatalf("assign fee = %d, want within [%d, %d]", task.AssignFee, tt.minFee, tt.maxFee)
+				}
+				if task.CompleteCost < tt.minCost || task.CompleteCost > tt.maxCost {
+					t.Fatalf("complete cost = %d, want within [%d, %d]", task.CompleteCost, tt.minCost, tt.maxCost)
+				}
+			}
+		})
+	}
+}
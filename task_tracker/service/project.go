@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+var (
+	ErrProjectNotFound       = errors.New("project not found")
+	ErrProjectExists         = errors.New("project already exists")
+	ErrProjectMemberNotFound = errors.New("project member not found")
+	ErrNoProjectWorkers      = errors.New("no project member found to assign tasks to")
+	ErrIllegalProjectChange  = errors.New("illegal project task change")
+)
+
+func (s Service) CreateProject(ctx context.Context, p model.Project) (*model.Project, error) {
+	_, err := s.projectRepo.GetByKey(ctx, p.Key)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProjectExists, p.Key)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	created, err := s.projectRepo.Create(ctx, *p.ToEntity())
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Project)
+	m.FromEntity(created)
+	return m, nil
+}
+
+func (s Service) GetProjects(ctx context.Context) ([]model.Project, error) {
+	projects, err := s.projectRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	projectsModel := make([]model.Project, len(projects))
+	for i, p := range projects {
+		projectsModel[i].FromEntity(&p)
+	}
+	return projectsModel, nil
+}
+
+func (s Service) GetProject(ctx context.Context, uuid string) (*model.Project, error) {
+	project, err := s.projectRepo.GetByID(ctx, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Project)
+	m.FromEntity(project)
+	return m, nil
+}
+
+func (s Service) UpdateProject(ctx context.Context, p model.Project) (*model.Project, error) {
+	updated, err := s.projectRepo.Update(ctx, *p.ToEntity())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Project)
+	m.FromEntity(updated)
+	return m, nil
+}
+
+// AddProjectMembers lets the tasks of the project be assigned to the users
+// and returns all the project members.
+func (s Service) AddProjectMembers(ctx context.Context, projectID string, userIDs []string) ([]model.ProjectMember, error) {
+	if err := s.checkProjectExists(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if err := s.projectRepo.AddMembers(ctx, projectID, userIDs...); err != nil {
+		return nil, err
+	}
+	return s.GetProjectMembers(ctx, projectID)
+}
+
+// RemoveProjectMember stops the tasks of the project from being assigned to
+// the user. The project tasks already assigned to the user stay with it until
+// the project is reassigned.
+func (s Service) RemoveProjectMember(ctx context.Context, projectID, userID string) error {
+	if err := s.checkProjectExists(ctx, projectID); err != nil {
+		return err
+	}
+	err := s.projectRepo.RemoveMember(ctx, projectID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProjectMemberNotFound
+	}
+	return err
+}
+
+func (s Service) GetProjectMembers(ctx context.Context, projectID string) ([]model.ProjectMember, error) {
+	if err := s.checkProjectExists(ctx, projectID); err != nil {
+		return nil, err
+	}
+	members, err := s.projectRepo.GetMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	membersModel := make([]model.ProjectMember, len(members))
+	for i, m := range members {
+		membersModel[i].FromEntity(&m)
+	}
+	return membersModel, nil
+}
+
+// CheckProjectAccess returns ErrForbidden unless the user is an admin or a
+// member of the project. Project members see all the tasks of the project.
+func (s Service) CheckProjectAccess(ctx context.Context, projectID, userID string, isAdmin bool) error {
+	if err := s.checkProjectExists(ctx, projectID); err != nil {
+		return err
+	}
+	if isAdmin {
+		return nil
+	}
+	isMember, err := s.projectRepo.IsMember(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// projectWorkers narrows down the workers to the members of the project.
+func (s Service) projectWorkers(ctx context.Context, projectID string, workers []db.Worker) ([]db.Worker, error) {
+	members, err := s.projectRepo.GetMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	isMember := make(map[string]bool, len(members))
+	for _, m := range members {
+		isMember[m.UserID] = true
+	}
+
+	var projectWorkers []db.Worker
+	for _, w := range workers {
+		if isMember[w.ID] {
+			projectWorkers = append(projectWorkers, w)
+		}
+	}
+	if len(projectWorkers) == 0 {
+		return nil, fmt.Errorf("%w: project %s", ErrNoProjectWorkers, projectID)
+	}
+	return projectWorkers, nil
+}
+
+// checkProjectTaskChange doesn't let a task move between projects or change
+// the Jira ID generated for a project task.
+func checkProjectTaskChange(current *db.Task, t *model.Task) error {
+	if t.ProjectID != "" && t.ProjectID != current.ProjectID {
+		return fmt.Errorf("%w: task can't be moved to another project", ErrIllegalProjectChange)
+	}
+	if current.ProjectID != "" && t.JiraID != "" && t.JiraID != current.JiraID {
+		return fmt.Errorf("%w: jira id of a project task can't be changed", ErrIllegalProjectChange)
+	}
+	return nil
+}
+
+func (s Service) checkProjectExists(ctx context.Context, projectID string) error {
+	_, err := s.projectRepo.GetByID(ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	return err
+}
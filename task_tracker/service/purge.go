@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// taskRetention is how long soft deleted tasks are kept for
+	taskRetention = 30 * 24 * time.Hour
+	purgeInterval = time.Hour
+)
+
+// RunPurgeJob periodically removes the tasks which have been soft deleted for
+// longer than taskRetention along with their attachments, the expired
+// idempotency keys and the task feed entries older than taskFeedRetention
+// until done is closed.
+func (s Service) RunPurgeJob(ctx context.Context, done chan bool) {
+	ticker := time.NewTicker(purgeInterval)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case tick := <-ticker.C:
+				// each cleanup goes on regardless of the others failing
+				if err := s.purgeDeletedTasks(ctx, tick.Add(-taskRetention)); err != nil {
+					log.Printf("failed to purge deleted tasks: %v\n", err)
+				}
+
+				expired, err := s.idempotencyRepo.DeleteExpired(ctx, tick.Add(-idempotencyKeyTTL))
+				if err != nil {
+					log.Printf("failed to delete expired idempotency keys: %v\n", err)
+				} else if expired > 0 {
+					log.Printf("%d expired idempotency keys are deleted\n", expired)
+				}
+
+				if _, err := s.feedRepo.DeleteBefore(ctx, tick.Add(-taskFeedRetention)); err != nil {
+					log.Printf("failed to delete old task feed entries: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// purgeDeletedTasks removes the tasks soft deleted before the given time along
+// with their attachments. The attachment contents are only deleted once the
+// tasks are gone for good.
+func (s Service) purgeDeletedTasks(ctx context.Context, deletedBefore time.Time) error {
+	var attachmentKeys []string
+	var purged int64
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		attachmentKeys, err = s.attachmentRepo.WithTx(tx).DeleteOfDeletedTasks(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+		purged, err = s.taskRepo.WithTx(tx).Purge(ctx, deletedBefore)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("%d deleted tasks are purged\n", purged)
+	}
+	for _, key := range attachmentKeys {
+		s.deleteBlob(ctx, key)
+	}
+	return nil
+}
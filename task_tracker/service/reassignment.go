@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/mq"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+// reassignmentPlanTTL is how long a previewed plan may be applied for.
+const reassignmentPlanTTL = 15 * time.Minute
+
+var (
+	ErrNoTasksToReassign = errors.New("no tasks to reassign")
+	ErrPlanNotFound      = errors.New("reassignment plan not found")
+	ErrStalePlan         = errors.New("reassignment plan can't be applied")
+)
+
+// ReassignTasks shuffles the open tasks between workers in a single
+// transaction. Unless projectID is empty, only the open tasks of the project
+// are reassigned. Besides a taskAssigned event per task, a single
+// tasksReassigned event describing the whole batch is published. The changes
+// are recorded in the task history on behalf of actorID.
+func (s Service) ReassignTasks(ctx context.Context, projectID, actorID string) (*model.ReassignmentPlan, error) {
+	workers, err := s.getReassignmentWorkers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *model.ReassignmentPlan
+	err = s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		tasks, err := s.taskRepo.WithTx(tx).LockOpen(ctx, projectID)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			log.Println(ErrNoTasksToReassign)
+			return ErrNoTasksToReassign
+		}
+
+		plan, err = s.planReassignment(ctx, s.projectRepo.WithTx(tx), projectID, tasks, workers)
+		if err != nil {
+			return err
+		}
+		return s.applyReassignment(ctx, tx, tasks, plan, actorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// PreviewReassignment makes a reassignment plan for the open tasks, or the
+// open tasks of the project unless projectID is empty, without changing them.
+// Only the plan itself is stored, so that it can be applied later by
+// ApplyReassignmentPlan exactly as it was previewed.
+func (s Service) PreviewReassignment(ctx context.Context, projectID string) (*model.ReassignmentPlan, error) {
+	workers, err := s.getReassignmentWorkers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.taskRepo.GetOpen(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		log.Println(ErrNoTasksToReassign)
+		return nil, ErrNoTasksToReassign
+	}
+
+	plan, err := s.planReassignment(ctx, s.projectRepo, projectID, tasks, workers)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := plan.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.planRepo.Create(ctx, *entity)
+	if err != nil {
+		return nil, err
+	}
+	plan.ID = created.ID
+	plan.Created = created.Created
+	return plan, nil
+}
+
+// ApplyReassignmentPlan reassigns the open tasks according to a previously
+// previewed plan. The plan is rejected with ErrStalePlan if it has expired,
+// has already been applied, the open tasks have changed since the preview or
+// some of the planned assignees may no longer be assigned their tasks.
+// A plan of a project may only be applied by the project members and admins.
+func (s Service) ApplyReassignmentPlan(ctx context.Context, uuid, actorID string, isAdmin bool) (*model.ReassignmentPlan, error) {
+	plan := new(model.ReassignmentPlan)
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		planRepo := s.planRepo.WithTx(tx)
+		entity, err := planRepo.GetByIDForUpdate(ctx, uuid, reassignmentPlanTTL)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlanNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if entity.AppliedAt != nil {
+			return fmt.Errorf("%w: already applied at %s", ErrStalePlan, entity.AppliedAt)
+		}
+		if entity.Expired {
+			return fmt.Errorf("%w: expired", ErrStalePlan)
+		}
+		if err := plan.FromEntity(entity); err != nil {
+			return err
+		}
+		if plan.ProjectID != "" {
+			if err := s.CheckProjectAccess(ctx, plan.ProjectID, actorID, isAdmin); err != nil {
+				return err
+			}
+		}
+
+		tasks, err := s.taskRepo.WithTx(tx).LockOpen(ctx, plan.ProjectID)
+		if err != nil {
+			return err
+		}
+		if err := plan.CheckUpToDate(tasks); err != nil {
+			return fmt.Errorf("%w: %v", ErrStalePlan, err)
+		}
+		if err := s.checkPlannedAssignees(ctx, s.projectRepo.WithTx(tx), plan, tasks); err != nil {
+			return err
+		}
+
+		if err := s.applyReassignment(ctx, tx, tasks, plan, actorID); err != nil {
+			return err
+		}
+		return planRepo.MarkApplied(ctx, uuid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// checkPlannedAssignees returns ErrStalePlan if some of the planned assignees
+// is not a worker anymore or has left the project of its task since the plan
+// was made.
+func (s Service) checkPlannedAssignees(ctx context.Context, projectRepo *db.ProjectRepo, plan *model.ReassignmentPlan, tasks []db.Task) error {
+	workers, err := s.getWorkers(ctx)
+	if err != nil {
+		return err
+	}
+	candidates, err := reassignmentCandidates(ctx, projectRepo, tasks, workers)
+	if err != nil {
+		return err
+	}
+
+	eligible := make(map[string]map[string]bool, len(candidates))
+	for projectID, indexes := range candidates {
+		eligible[projectID] = make(map[string]bool, len(indexes))
+		for _, i := range indexes {
+			eligible[projectID][workers[i].ID] = true
+		}
+	}
+	taskProjects := make(map[string]string, len(tasks))
+	for _, t := range tasks {
+		taskProjects[t.ID] = t.ProjectID
+	}
+	for _, a := range plan.Assignments {
+		if !eligible[taskProjects[a.TaskID]][a.AssigneeID] {
+			return fmt.Errorf("%w: task %s can't be assigned to %s anymore", ErrStalePlan, a.TaskID, a.AssigneeID)
+		}
+	}
+	return nil
+}
+
+// getReassignmentWorkers returns the workers the tasks may be reassigned to.
+// Unless projectID is empty, the project must exist and only its members are
+// returned.
+func (s Service) getReassignmentWorkers(ctx context.Context, projectID string) ([]db.Worker, error) {
+	workers, err := s.getWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if projectID == "" {
+		return workers, nil
+	}
+	if err := s.checkProjectExists(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.projectWorkers(ctx, projectID, workers)
+}
+
+// planReassignment picks a new assignee for every task. The tasks of a
+// project only go to the members of the project.
+func (s Service) planReassignment(ctx context.Context, projectRepo *db.ProjectRepo, projectID string, tasks []db.Task, workers []db.Worker) (*model.ReassignmentPlan, error) {
+	// all the open tasks are redistributed, so workers start from an empty board
+	for i := range workers {
+		workers[i].OpenTasks = 0
+	}
+
+	candidates, err := reassignmentCandidates(ctx, projectRepo, tasks, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make([]model.PlannedAssignment, len(tasks))
+	for i, task := range tasks {
+		assignee, err := s.pickCandidate(workers, candidates[task.ProjectID])
+		if err != nil {
+			return nil, fmt.Errorf("%w: project %s", err, task.ProjectID)
+		}
+		assignee.OpenTasks++
+		assignments[i] = model.PlannedAssignment{
+			TaskID:             task.ID,
+			Title:              task.Title,
+			JiraID:             task.JiraID,
+			PreviousAssigneeID: task.AssigneeID,
+			AssigneeID:         assignee.ID,
+			AssignFee:          task.AssignFee,
+		}
+	}
+	return model.NewReassignmentPlan(projectID, assignments), nil
+}
+
+// reassignmentCandidates maps the projects of the tasks to the ascending
+// indexes of the workers who are their members. The tasks out of any project, keyed with an
+// empty project ID, may go to any worker.
+func reassignmentCandidates(ctx context.Context, projectRepo *db.ProjectRepo, tasks []db.Task, workers []db.Worker) (map[string][]int, error) {
+	candidates := make(map[string][]int)
+	var projectIDs []string
+	for _, t := range tasks {
+		if _, ok := candidates[t.ProjectID]; ok {
+			continue
+		}
+		candidates[t.ProjectID] = []int{}
+		if t.ProjectID != "" {
+			projectIDs = append(projectIDs, t.ProjectID)
+		}
+	}
+	for i := range workers {
+		candidates[""] = append(candidates[""], i)
+	}
+
+	members, err := projectRepo.GetMembersOf(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	workerIndexes := make(map[string]int, len(workers))
+	for i, w := range workers {
+		workerIndexes[w.ID] = i
+	}
+	for _, m := range members {
+		if i, ok := workerIndexes[m.UserID]; ok {
+			candidates[m.ProjectID] = append(candidates[m.ProjectID], i)
+		}
+	}
+	// workers are sorted by ID, so are the candidates with ascending indexes
+	for _, indexes := range candidates {
+		sort.Ints(indexes)
+	}
+	return candidates, nil
+}
+
+// pickCandidate lets the assignment strategy pick one of the workers with the
+// given indexes. The picked worker is returned from workers, so that the open
+// task counters are shared by all the candidate subsets. The indexes must be
+// ascending, so that the candidates stay sorted by ID as the strategies
+// expect.
+func (s Service) pickCandidate(workers []db.Worker, indexes []int) (*db.Worker, error) {
+	if len(indexes) == 0 {
+		return nil, ErrNoProjectWorkers
+	}
+	candidates := make([]db.Worker, len(indexes))
+	for i, idx := range indexes {
+		candidates[i] = workers[idx]
+	}
+	picked := s.strategy.Pick(candidates)
+	for _, idx := range indexes {
+		if workers[idx].ID == picked.ID {
+			return &workers[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("assignment strategy picked unknown worker %s", picked.ID)
+}
+
+// applyReassignment updates tasks according to plan within tx and enqueues
+// the reassignment events.
+func (s Service) applyReassignment(ctx context.Context, tx *sqlx.Tx, tasks []db.Task, plan *model.ReassignmentPlan, actorID string) error {
+	newAssignees := make(map[string]string, len(plan.Assignments))
+	for _, a := range plan.Assignments {
+		newAssignees[a.TaskID] = a.AssigneeID
+	}
+
+	if err := fillTaskLabels(ctx, s.labelRepo.WithTx(tx), tasks); err != nil {
+		return err
+	}
+
+	taskRepo := s.taskRepo.WithTx(tx)
+	reassignedTaskEvents := make([]mq.TaskEvent, len(tasks))
+	batch := model.ReassignmentInfo{
+		Tasks: make([]model.ReassignedTaskInfo, len(tasks)),
+	}
+	for i, task := range tasks {
+		prev := task
+		task.AssigneeID = newAssignees[task.ID]
+		if task.Status == model.TaskStatusOpen {
+			task.Status = model.TaskStatusAssigned
+		}
+
+		updated, err := taskRepo.Update(ctx, task)
+		if err != nil {
+			err = fmt.Errorf("failed to reassign task %s: %v", task.ID, err)
+			return err
+		}
+		if err := s.recordChanges(ctx, tx, &prev, updated, actorID); err != nil {
+			return err
+		}
+		info := model.TaskEntityToTaskInfo(updated)
+		reassignedTaskEvents[i] = mq.TaskEvent{
+			Name:    mq.TaskAssignedEvent,
+			Version: taskSchemaVersion,
+			Data:    *info,
+		}
+		batch.Tasks[i] = model.ReassignedTaskInfo{
+			TaskInfo:           *info,
+			PreviousAssigneeID: prev.AssigneeID,
+		}
+	}
+
+	if err := s.enqueueTaskEvents(ctx, tx, reassignedTaskEvents...); err != nil {
+		return err
+	}
+	e := mq.ReassignmentEvent{
+		Name:    mq.TasksReassignedEvent,
+		Version: reassignmentSchemaVersion,
+		Data:    batch,
+	}
+	// the batch spans many tasks, so it has no key to be ordered by
+	return s.enqueueEvent(ctx, tx, "", e.Name, e, reassignmentSchemaType, e.Version)
+}
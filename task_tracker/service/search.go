@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+// SearchTasks finds tasks by the words of their title, jira id and
+// description. Unless allTasks is set, only the tasks assigned to userID are
+// searched.
+func (s Service) SearchTasks(ctx context.Context, q model.TaskSearchQuery, userID string, allTasks bool) ([]model.TaskSearchHit, error) {
+	assigneeID := userID
+	if allTasks {
+		assigneeID = ""
+	}
+	hits, err := s.taskRepo.Search(ctx, q.Query, assigneeID, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*db.Task, len(hits))
+	for i := range hits {
+		tasks[i] = &hits[i].Task
+	}
+	if err := s.labelRepo.FillTaskLabels(ctx, tasks...); err != nil {
+		return nil, err
+	}
+
+	hitsModel := make([]model.TaskSearchHit, len(hits))
+	for i, h := range hits {
+		m := new(model.TaskSearchHit)
+		m.FromEntity(&h)
+		hitsModel[i] = *m
+	}
+	return hitsModel, nil
+}
@@ -2,55 +2,199 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
+	"strconv"
 	"time"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/jmoiron/sqlx"
 	"github.com/ko3luhbka/popug_schema_registry/validator"
+	"github.com/segmentio/kafka-go"
 
 	"github.com/ko3luhbka/task_tracker/db"
 	"github.com/ko3luhbka/task_tracker/mq"
 	"github.com/ko3luhbka/task_tracker/rest/model"
+	"github.com/ko3luhbka/task_tracker/storage"
 )
 
-const taskSchemaType = "task"
+const (
+	taskSchemaType    = "task"
+	taskSchemaVersion = 10
 
-type Service struct {
-	taskRepo     *db.TaskRepo
-	assigneeRepo *db.AssigneeRepo
-	Mq           *mq.Client
-}
+	reassignmentSchemaType    = "tasks_reassigned"
+	reassignmentSchemaVersion = 1
 
-func NewService(tr *db.TaskRepo, ar *db.AssigneeRepo, mq *mq.Client) *Service {
-	return &Service{
-		taskRepo:     tr,
-		assigneeRepo: ar,
-		Mq:           mq,
+	outboxRelayInterval = 5 * time.Second
+	outboxBatchSize     = 100
+)
+
+var (
+	ErrIllegalTransition = errors.New("illegal task status transition")
+	ErrVersionMismatch   = errors.New("task has been changed since it was read")
+
+	// errDryRun rolls back the transaction of a task which is only checked
+	// whether it can be created
+	errDryRun = errors.New("dry run")
+)
+
+type (
+	Config struct {
+		// AssignmentStrategy is one of the *Strategy constants
+		AssignmentStrategy string
 	}
-}
+	// Repos bundles the storage the service works with.
+	Repos struct {
+		TxManager    *db.TxManager
+		Task         *db.TaskRepo
+		Assignee     *db.AssigneeRepo
+		Outbox       *db.OutboxRepo
+		Plan         *db.ReassignmentPlanRepo
+		Comment      *db.CommentRepo
+		History      *db.HistoryRepo
+		Label        *db.LabelRepo
+		Link         *db.LinkRepo
+		Idempotency  *db.IdempotencyRepo
+		Webhook      *db.WebhookRepo
+		Feed         *db.FeedRepo
+		Attachment   *db.AttachmentRepo
+		Blobs        storage.BlobStore
+		Watcher      *db.WatcherRepo
+		Notification *db.NotificationRepo
+		Project      *db.ProjectRepo
+	}
+	Service struct {
+		txManager        *db.TxManager
+		taskRepo         *db.TaskRepo
+		assigneeRepo     *db.AssigneeRepo
+		outboxRepo       *db.OutboxRepo
+		planRepo         *db.ReassignmentPlanRepo
+		commentRepo      *db.CommentRepo
+		historyRepo      *db.HistoryRepo
+		labelRepo        *db.LabelRepo
+		linkRepo         *db.LinkRepo
+		idempotencyRepo  *db.IdempotencyRepo
+		webhookRepo      *db.WebhookRepo
+		feedRepo         *db.FeedRepo
+		attachmentRepo   *db.AttachmentRepo
+		blobs            storage.BlobStore
+		watcherRepo      *db.WatcherRepo
+		notificationRepo *db.NotificationRepo
+		projectRepo      *db.ProjectRepo
+		strategy         AssignmentStrategy
+		Mq               *mq.Client
+	}
+)
 
-func (s Service) CreateTask(ctx context.Context, t model.Task) (*model.Task, error) {
-	assignee, err := s.getRandomAssignee(ctx)
+func NewService(cfg *Config, repos *Repos, mq *mq.Client) (*Service, error) {
+	strategy, err := NewAssignmentStrategy(cfg.AssignmentStrategy)
 	if err != nil {
 		return nil, err
 	}
-	t.AssigneeID = assignee.ID
-	t.Status = model.TaskStatusAssigned
 
-	created, err := s.taskRepo.Create(ctx, *t.ToEntity())
+	return &Service{
+		txManager:        repos.TxManager,
+		taskRepo:         repos.Task,
+		assigneeRepo:     repos.Assignee,
+		outboxRepo:       repos.Outbox,
+		planRepo:         repos.Plan,
+		commentRepo:      repos.Comment,
+		historyRepo:      repos.History,
+		labelRepo:        repos.Label,
+		linkRepo:         repos.Link,
+		idempotencyRepo:  repos.Idempotency,
+		webhookRepo:      repos.Webhook,
+		feedRepo:         repos.Feed,
+		attachmentRepo:   repos.Attachment,
+		blobs:            repos.Blobs,
+		watcherRepo:      repos.Watcher,
+		notificationRepo: repos.Notification,
+		projectRepo:      repos.Project,
+		strategy:         strategy,
+		Mq:               mq,
+	}, nil
+}
+
+// CreateTask assigns the task to a worker picked by the assignment strategy.
+// The creator and the assignee watch the task from then on. Only the project
+// members and admins may add tasks to a project, ErrForbidden is returned to
+// the others.
+func (s Service) CreateTask(ctx context.Context, t model.Task, creatorID string, isAdmin bool) (*model.Task, error) {
+	return s.createTask(ctx, t, creatorID, isAdmin, false)
+}
+
+// createTask creates the task. With dryRun set the task goes through all the
+// same checks, but the transaction creating it is rolled back and nil is
+// returned instead of the task.
+func (s Service) createTask(ctx context.Context, t model.Task, creatorID string, isAdmin, dryRun bool) (*model.Task, error) {
+	workers, err := s.getWorkers(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if t.ProjectID != "" {
+		if err := s.CheckProjectAccess(ctx, t.ProjectID, creatorID, isAdmin); err != nil {
+			return nil, err
+		}
+		if workers, err = s.projectWorkers(ctx, t.ProjectID, workers); err != nil {
+			return nil, err
+		}
+	}
+	t.AssigneeID = s.strategy.Pick(workers).ID
+	t.Status = model.TaskStatusAssigned
+	priceTask(&t)
 
-	e := mq.TaskEvent{
-		Name: mq.TaskAssignedEvent,
-		Version: 2,
-		Data: *model.TaskEntityToTaskInfo(created),
+	var created *db.Task
+	err = s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if t.ParentID != "" {
+			if err := s.checkParent(ctx, tx, "", t.ParentID); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if t.ProjectID != "" {
+			if t.JiraID, err = s.projectRepo.WithTx(tx).NextJiraID(ctx, t.ProjectID); err != nil {
+				return err
+			}
+		}
+		created, err = s.taskRepo.WithTx(tx).Create(ctx, *t.ToEntity())
+		if err != nil {
+			return err
+		}
+		if err := s.setTaskLabels(ctx, tx, created, t.Labels); err != nil {
+			return err
+		}
+		if err := s.addToFeed(ctx, tx, nil, created); err != nil {
+			return err
+		}
+		if creatorID != "" {
+			if err := s.watcherRepo.WithTx(tx).Add(ctx, created.ID, creatorID); err != nil {
+				return err
+			}
+		}
+		if err := s.notifyTaskChanged(ctx, tx, nil, created, creatorID, nil); err != nil {
+			return err
+		}
+
+		e := mq.TaskEvent{
+			Name:    mq.TaskAssignedEvent,
+			Version: taskSchemaVersion,
+			Data:    *model.TaskEntityToTaskInfo(created),
+		}
+		if err := s.enqueueTaskEvents(ctx, tx, e); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if dryRun && errors.Is(err, errDryRun) {
+		return nil, nil
 	}
-	if err := s.ProduceMsg(ctx, e); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -59,48 +203,126 @@ func (s Service) CreateTask(ctx context.Context, t model.Task) (*model.Task, err
 	return m, nil
 }
 
-func (s Service) GetTaskByID(ctx context.Context, uuid string) (*model.Task, error) {
+// GetTaskByID returns the task. Tasks of a project are only shown to the
+// project members and admins, ErrForbidden is returned to the others. Soft
+// deleted tasks are only shown to admins.
+func (s Service) GetTaskByID(ctx context.Context, uuid, userID string, isAdmin bool) (*model.Task, error) {
 	task, err := s.taskRepo.GetByID(ctx, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
+	if task.DeletedAt != nil && !isAdmin {
+		return nil, ErrTaskNotFound
+	}
+	if task.ProjectID != "" {
+		if err := s.CheckProjectAccess(ctx, task.ProjectID, userID, isAdmin); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.labelRepo.FillTaskLabels(ctx, task); err != nil {
+		return nil, err
+	}
 
 	m := new(model.Task)
 	m.FromEntity(task)
 	return m, nil
 }
 
-func (s Service) GetAllTasks(ctx context.Context) ([]model.Task, error) {
-	tasks, err := s.taskRepo.GetAll(ctx)
+// ListTasks returns a single page of tasks matching f along with the cursor
+// of the next page, if there is one.
+func (s Service) ListTasks(ctx context.Context, f *db.TaskFilter) (*model.TaskPage, error) {
+	limit := f.Limit
+	// fetch one extra task to find out whether there is a next page
+	f.Limit++
+	tasks, err := s.taskRepo.List(ctx, *f)
 	if err != nil {
 		return nil, err
 	}
 
-	tasksModel := make([]model.Task, len(tasks))
+	page := new(model.TaskPage)
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		page.Next = model.NextTaskCursor(f, &tasks[limit-1])
+	}
+	if err := fillTaskLabels(ctx, s.labelRepo, tasks); err != nil {
+		return nil, err
+	}
+
+	page.Tasks = make([]model.Task, len(tasks))
 	for i, task := range tasks {
 		m := new(model.Task)
 		m.FromEntity(&task)
-		tasksModel[i] = *m
+		page.Tasks[i] = *m
 	}
-	return tasksModel, nil
+	return page, nil
 }
 
-func (s Service) UpdateTask(ctx context.Context, t model.Task) (*model.Task, error) {
+// UpdateTask updates the task and records the changed fields on behalf of
+// actorID in the task history. If versions are given, ErrVersionMismatch is
+// returned unless one of them is the current version of the task.
+func (s Service) UpdateTask(ctx context.Context, t model.Task, versions []int, actorID string) (*model.Task, error) {
 	t.RemoveAssignee()
-	updated, err := s.taskRepo.Update(ctx, *t.ToEntity())
-	if err != nil {
-		return nil, err
-	}
 
-	if updated.Status == model.TaskStatusCompleted {
-		e := mq.TaskEvent{
-			Name: mq.TaskCompleted,
-			Version: 2,
-			Data: *model.TaskEntityToTaskInfo(updated),
+	var updated *db.Task
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		taskRepo := s.taskRepo.WithTx(tx)
+		current, err := taskRepo.GetByIDForUpdate(ctx, t.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
 		}
-		if err := s.ProduceMsg(ctx, e); err != nil {
-			return nil, err
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt != nil {
+			return ErrTaskNotFound
+		}
+		if len(versions) != 0 && !containsVersion(versions, current.Version) {
+			return fmt.Errorf("%w: versions %v are requested, but the current one is %d", ErrVersionMismatch, versions, current.Version)
+		}
+		if err := s.labelRepo.WithTx(tx).FillTaskLabels(ctx, current); err != nil {
+			return err
+		}
+		if t.Status == current.Status {
+			t.Status = ""
+		}
+		if t.Status != "" && !model.CanTransition(current.Status, t.Status) {
+			return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, current.Status, t.Status)
+		}
+		if t.Status == model.TaskStatusCompleted {
+			if err := s.checkCanComplete(ctx, tx, t.ID); err != nil {
+				return err
+			}
+		}
+		if err := checkProjectTaskChange(current, &t); err != nil {
+			return err
+		}
+		if t.ParentID != "" && t.ParentID != current.ParentID {
+			if err := s.checkParent(ctx, tx, t.ID, t.ParentID); err != nil {
+				return err
+			}
+		}
+
+		updated, err = taskRepo.Update(ctx, *t.ToEntity())
+		if err != nil {
+			return err
+		}
+		updated.Labels = current.Labels
+		if t.Labels != nil {
+			if err := s.setTaskLabels(ctx, tx, updated, t.Labels); err != nil {
+				return err
+			}
 		}
+		if err := s.recordChanges(ctx, tx, current, updated, actorID); err != nil {
+			return err
+		}
+
+		return s.enqueueTaskEvents(ctx, tx, taskChangedEvents(current, updated)...)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	m := new(model.Task)
@@ -108,68 +330,141 @@ func (s Service) UpdateTask(ctx context.Context, t model.Task) (*model.Task, err
 	return m, nil
 }
 
-func (s Service) DeleteTask(ctx context.Context, uuid string) error {
-	return s.taskRepo.Delete(ctx, uuid)
+func containsVersion(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
 }
 
-func (s Service) ReassignTasks(ctx context.Context) error {
-	tasks, err := s.taskRepo.GetAll(ctx)
-	if err != nil {
-		return err
+// taskChangedEvents returns the events telling about the task updated from
+// the prev state: status changes are reported by statusChangedEvents, any
+// other change by a taskUpdated event.
+func taskChangedEvents(prev, task *db.Task) []mq.TaskEvent {
+	if task.Status != prev.Status {
+		return statusChangedEvents(prev, task)
 	}
+	return []mq.TaskEvent{{
+		Name:    mq.TaskUpdatedEvent,
+		Version: taskSchemaVersion,
+		Data:    *model.TaskEntityToTaskInfo(task),
+	}}
+}
 
-	if len(tasks) == 0 {
-		err := fmt.Errorf("no tasks to reassign")
-		log.Println(err)
-		return err
+// statusChangedEvents returns a taskStatusChanged event for the task moved
+// from the prev state, followed by taskCompleted if the task has been
+// completed for the first time. A reopened task isn't paid for once again.
+func statusChangedEvents(prev, task *db.Task) []mq.TaskEvent {
+	info := model.TaskEntityToTaskInfo(task)
+	info.PreviousStatus = prev.Status
+	events := []mq.TaskEvent{{
+		Name:    mq.TaskStatusChangedEvent,
+		Version: taskSchemaVersion,
+		Data:    *info,
+	}}
+
+	if task.Status == model.TaskStatusCompleted && prev.CompletedAt == nil {
+		events = append(events, mq.TaskEvent{
+			Name:    mq.TaskCompleted,
+			Version: taskSchemaVersion,
+			Data:    *model.TaskEntityToTaskInfo(task),
+		})
 	}
+	return events
+}
 
-	reassignedTaskEvents := make([]mq.TaskEvent, len(tasks))
-	for i, task := range tasks {
-		assignee, err := s.getRandomAssignee(ctx)
+// DeleteTask soft deletes the task. The task is removed for good by the purge
+// job once taskRetention has passed, unless it's restored before.
+func (s Service) DeleteTask(ctx context.Context, uuid, actorID string) error {
+	return s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		deleted, err := s.taskRepo.WithTx(tx).SoftDelete(ctx, uuid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
 		if err != nil {
 			return err
 		}
-		task.AssigneeID = assignee.ID
-		updated, err := s.taskRepo.Update(ctx, task)
+		prev := *deleted
+		prev.DeletedAt = nil
+		return s.onDeletedChanged(ctx, tx, &prev, deleted, mq.TaskDeletedEvent, actorID)
+	})
+}
+
+// RestoreTask brings back a soft deleted task.
+func (s Service) RestoreTask(ctx context.Context, uuid, actorID string) (*model.Task, error) {
+	var restored *db.Task
+	err := s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		taskRepo := s.taskRepo.WithTx(tx)
+		current, err := taskRepo.GetByIDForUpdate(ctx, uuid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
 		if err != nil {
-			err = fmt.Errorf("failed to reassign task %s: %v", task.ID, err)
 			return err
 		}
-		reassignedTaskEvents[i] = mq.TaskEvent{
-			Name: mq.TaskAssignedEvent,
-			Version: 2,
-			Data: model.TaskInfo{
-				ID:         updated.ID,
-				Title: updated.Title,
-				JiraID: updated.JiraID,
-				AssigneeID: assignee.ID,
-			},
+		restored, err = taskRepo.Restore(ctx, uuid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
 		}
+		if err != nil {
+			return err
+		}
+		return s.onDeletedChanged(ctx, tx, current, restored, mq.TaskRestoredEvent, actorID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.ProduceMsg(ctx, reassignedTaskEvents...); err != nil {
+	m := new(model.Task)
+	m.FromEntity(restored)
+	return m, nil
+}
+
+// onDeletedChanged records the deletion or restoration of the task in its
+// history and enqueues the corresponding event.
+func (s Service) onDeletedChanged(ctx context.Context, tx *sqlx.Tx, prev, task *db.Task, eventName, actorID string) error {
+	if err := s.labelRepo.WithTx(tx).FillTaskLabels(ctx, prev, task); err != nil {
+		return err
+	}
+	if err := s.recordChanges(ctx, tx, prev, task, actorID); err != nil {
 		return err
 	}
 
-	return nil
+	e := mq.TaskEvent{
+		Name:    eventName,
+		Version: taskSchemaVersion,
+		Data:    *model.TaskEntityToTaskInfo(task),
+	}
+	return s.enqueueTaskEvents(ctx, tx, e)
 }
 
-func (s Service) getRandomAssignee(ctx context.Context) (*db.Assignee, error) {
-	assignees, err := s.assigneeRepo.GetAll(ctx)
+// UpdateAssigneeCapacity sets the capacity used by the weighted assignment strategy.
+func (s Service) UpdateAssigneeCapacity(ctx context.Context, uuid string, capacity int) (*model.Assignee, error) {
+	updated, err := s.assigneeRepo.Update(ctx, db.Assignee{ID: uuid, Capacity: capacity})
 	if err != nil {
 		return nil, err
 	}
 
-	if len(assignees) == 0 {
+	m := new(model.Assignee)
+	m.FromEntity(updated)
+	return m, nil
+}
+
+// getWorkers returns the assignees tasks can be assigned to.
+func (s Service) getWorkers(ctx context.Context) ([]db.Worker, error) {
+	workers, err := s.assigneeRepo.GetWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(workers) == 0 {
 		err := fmt.Errorf("no assignee found to assign tasks to")
 		log.Println(err)
 		return nil, err
 	}
-
-	rand.Seed(time.Now().UnixNano())
-	idx := rand.Intn(len(assignees))
-	return &assignees[idx], nil
+	return workers, nil
 }
 
 func (s Service) ConsumeMsg(errCh chan error) {
@@ -196,31 +491,130 @@ func (s Service) ConsumeMsg(errCh chan error) {
 	}(errCh)
 }
 
-func (s Service) ProduceMsg(ctx context.Context, events ...mq.TaskEvent) error {
-	
-	msgs := make([]kafka.Message, len(events))
-	for i, e := range events {
-		if err := validator.Validate(e, taskSchemaType, 2); err != nil {
-			log.Println(err)
-			return fmt.Errorf("invalid event: %v", err)
+// enqueueTaskEvents validates events against the task schema and stores them
+// in the outbox within tx, so they are only published if tx commits.
+func (s Service) enqueueTaskEvents(ctx context.Context, tx *sqlx.Tx, events ...mq.TaskEvent) error {
+	for _, e := range events {
+		if err := s.enqueueEvent(ctx, tx, e.Data.ID, e.Name, e, taskSchemaType, e.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// enqueueEvent validates event e against the given schema and stores it in
+// the outbox along with the webhook deliveries of it within tx, so it is only
+// published if tx commits. The events of a task are keyed by the task ID, so
+// that they are consumed in order.
+func (s Service) enqueueEvent(ctx context.Context, tx *sqlx.Tx, key, name string, e any, schemaType string, schemaVersion int) error {
+	payload, err := s.enqueueEventTo(ctx, tx, mq.TasksTopic, key, name, e, schemaType, schemaVersion)
+	if err != nil {
+		return err
+	}
+	if err := s.webhookRepo.WithTx(tx).CreateDeliveries(ctx, name, payload); err != nil {
+		return fmt.Errorf("failed to schedule webhook deliveries: %v", err)
+	}
+	return nil
+}
+
+// enqueueEventTo validates event e against the given schema and stores it in
+// the outbox of the topic under the message key within tx. The marshaled
+// event is returned.
+func (s Service) enqueueEventTo(ctx context.Context, tx *sqlx.Tx, topic, key, name string, e any, schemaType string, schemaVersion int) ([]byte, error) {
+	if err := validator.Validate(e, schemaType, schemaVersion); err != nil {
+		log.Println(err)
+		return nil, fmt.Errorf("invalid event: %v", err)
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Kafka event: %v", err)
+	}
+	msg := db.OutboxMessage{
+		Topic:     topic,
+		Key:       key,
+		EventName: name,
+		Payload:   payload,
+	}
+	if _, err := s.outboxRepo.WithTx(tx).Create(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to store event in outbox: %v", err)
+	}
+	return payload, nil
+}
+
+// RunOutboxRelay periodically publishes pending outbox messages to Kafka
+// until done is closed. Messages that fail to be published stay pending and
+// are retried on the next tick.
+func (s Service) RunOutboxRelay(ctx context.Context, done chan bool) {
+	ticker := time.NewTicker(outboxRelayInterval)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := s.relayOutbox(ctx); err != nil {
+					log.Printf("failed to relay outbox messages: %v\n", err)
+				}
+			}
 		}
+	}()
+}
 
-		msgValue, err := json.Marshal(e)
+// relayOutbox publishes a batch of pending outbox messages with a single
+// write, so that the batch is not held back by the writer batch timeout for
+// every message while the outbox rows are locked.
+func (s Service) relayOutbox(ctx context.Context) error {
+	return s.txManager.WithTx(ctx, func(tx *sqlx.Tx) error {
+		outbox := s.outboxRepo.WithTx(tx)
+		msgs, err := outbox.LockPending(ctx, outboxBatchSize)
 		if err != nil {
-			return fmt.Errorf("failed to marshal Kafka event: %v", err)
+			return err
 		}
-		msg := kafka.Message{
-			Key:   nil,
-			Value: msgValue,
+		if len(msgs) == 0 {
+			return nil
 		}
-		msgs[i] = msg
-	}
 
-	if err := s.Mq.Writer.WriteMessages(ctx, msgs...); err != nil {
-		log.Fatal("failed to write message:", err)
-	}
-	return nil
+		batch := make([]kafka.Message, len(msgs))
+		for i, m := range msgs {
+			batch[i] = kafka.Message{
+				Topic: m.Topic,
+				Value: m.Payload,
+				Headers: []kafka.Header{
+					{Key: mq.OutboxIDHeader, Value: []byte(strconv.FormatInt(m.ID, 10))},
+				},
+			}
+			if m.Key != "" {
+				batch[i].Key = []byte(m.Key)
+			}
+		}
+		// only the failed messages are retried later: publishing the sent ones
+		// once again would make the consumers handle them twice
+		writeErr := s.Mq.Writer.WriteMessages(ctx, batch...)
+		var writeErrs kafka.WriteErrors
+		if writeErr != nil && !errors.As(writeErr, &writeErrs) {
+			writeErrs = make(kafka.WriteErrors, len(msgs))
+			for i := range writeErrs {
+				writeErrs[i] = writeErr
+			}
+		}
+
+		for i, m := range msgs {
+			if writeErrs != nil && writeErrs[i] != nil {
+				log.Printf("failed to publish outbox message %d: %v\n", m.ID, writeErrs[i])
+				if err := outbox.MarkFailed(ctx, m.ID, writeErrs[i]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := outbox.MarkSent(ctx, m.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (s Service) handleEvent(ctx context.Context, msg *kafka.Message) error {
@@ -237,6 +631,10 @@ func (s Service) handleEvent(ctx context.Context, msg *kafka.Message) error {
 		}
 	case mq.UserUpdatedEvent:
 		_, err := s.assigneeRepo.Update(ctx, *e.Data.ToEntity())
+		if errors.Is(err, sql.ErrNoRows) {
+			// the user was created before we started consuming, so add it now
+			_, err = s.assigneeRepo.Create(ctx, *e.Data.ToEntity())
+		}
 		if err != nil {
 			return fmt.Errorf("failed to update incoming assignee: %v", err)
 		}
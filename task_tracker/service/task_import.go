@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+// ImportTasks creates a task for every valid row, each in its own
+// transaction, so that a bad row doesn't prevent the others from being
+// imported. With dryRun set every row goes through the same checks, but no
+// task is created.
+func (s Service) ImportTasks(ctx context.Context, rows []model.ImportRow, dryRun bool, actorID string, isAdmin bool) (*model.ImportReport, error) {
+	report := &model.ImportReport{
+		DryRun: dryRun,
+		Rows:   make([]model.ImportRowResult, len(rows)),
+	}
+	for i, row := range rows {
+		result := model.ImportRowResult{Line: row.Line}
+		err := row.Err
+		if err == nil {
+			err = row.Task.ValidateCreate()
+		}
+		if err == nil {
+			var created *model.Task
+			created, err = s.createTask(ctx, row.Task, actorID, isAdmin, dryRun)
+			if created != nil {
+				result.TaskID = created.ID
+			}
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			report.Rejected++
+		} else {
+			report.Accepted++
+		}
+		report.Rows[i] = result
+	}
+
+	log.Printf("task import: %d rows accepted, %d rejected, dry run: %t\n", report.Accepted, report.Rejected, dryRun)
+	return report, nil
+}
+
+// ExportTasks passes all the tasks matching the filter to write page by page.
+func (s Service) ExportTasks(ctx context.Context, f *db.TaskFilter, write func(tasks []model.Task) error) error {
+	limit := f.Limit
+	for {
+		// ListTasks changes the limit to look ahead
+		f.Limit = limit
+		page, err := s.ListTasks(ctx, f)
+		if err != nil {
+			return err
+		}
+		if err := write(page.Tasks); err != nil {
+			return err
+		}
+		if page.Next == "" {
+			return nil
+		}
+		last := page.Tasks[len(page.Tasks)-1]
+		f.CursorValue, f.CursorID = model.TaskCursorPosition(f, last.ToEntity())
+	}
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+	"github.com/ko3luhbka/task_tracker/mq"
+	"github.com/ko3luhbka/task_tracker/rest/model"
+)
+
+const (
+	webhookDispatchInterval = 5 * time.Second
+	webhookBatchSize        = 50
+	webhookTimeout          = 10 * time.Second
+	// webhookLease is how long a claimed delivery is hidden from other
+	// dispatchers, it must outlast webhookTimeout
+	webhookLease = time.Minute
+
+	// a failed delivery is retried after webhookBaseBackoff, doubling the delay
+	// on every next failure up to webhookMaxBackoff
+	webhookBaseBackoff = 10 * time.Second
+	webhookMaxBackoff  = time.Hour
+	webhookMaxAttempts = 8
+
+	webhookDeliveriesLimit = 100
+	webhookSecretLength    = 32
+
+	webhookTestEvent = "webhookTest"
+
+	WebhookEventHeader     = "X-Popug-Event"
+	WebhookDeliveryHeader  = "X-Popug-Delivery"
+	WebhookTimestampHeader = "X-Popug-Timestamp"
+	WebhookSignatureHeader = "X-Popug-Signature"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrUnknownEventType        = errors.New("unknown event type")
+)
+
+// webhookEventTypes are the events which can be subscribed to.
+var webhookEventTypes = map[string]struct{}{
+	mq.TaskAssignedEvent:      {},
+	mq.TaskCompleted:          {},
+	mq.TaskStatusChangedEvent: {},
+	mq.TaskUpdatedEvent:       {},
+	mq.TasksReassignedEvent:   {},
+	mq.TaskCommentedEvent:     {},
+	mq.TaskOverdueEvent:       {},
+	mq.TaskDeletedEvent:       {},
+	mq.TaskRestoredEvent:      {},
+}
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// CreateWebhook subscribes the URL to the events. A secret to sign the
+// deliveries with is generated unless given, and is only returned here.
+func (s Service) CreateWebhook(ctx context.Context, w model.Webhook) (*model.Webhook, error) {
+	for _, et := range w.EventTypes {
+		if _, ok := webhookEventTypes[et]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, et)
+		}
+	}
+	if w.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		w.Secret = secret
+	}
+
+	e, err := w.ToEntity()
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.webhookRepo.CreateSubscription(ctx, *e)
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.Webhook)
+	if err := m.FromEntity(created); err != nil {
+		return nil, err
+	}
+	m.Secret = created.Secret
+	return m, nil
+}
+
+func (s Service) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	subs, err := s.webhookRepo.GetSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]model.Webhook, len(subs))
+	for i, sub := range subs {
+		if err := webhooks[i].FromEntity(&sub); err != nil {
+			return nil, err
+		}
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook unsubscribes the webhook and drops its deliveries.
+func (s Service) DeleteWebhook(ctx context.Context, uuid string) error {
+	err := s.webhookRepo.DeleteSubscription(ctx, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// TestWebhook sends a webhookTest event to the webhook right away and returns
+// the outcome. Failed test deliveries are retried like any other.
+func (s Service) TestWebhook(ctx context.Context, uuid string) (*model.WebhookDelivery, error) {
+	sub, err := s.webhookRepo.GetSubscriptionByID(ctx, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"name": webhookTestEvent,
+		"data": map[string]string{"webhook_id": sub.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.webhookRepo.CreateDelivery(ctx, db.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		EventName:      webhookTestEvent,
+		Payload:        payload,
+	}, webhookLease)
+	if err != nil {
+		return nil, err
+	}
+
+	due := db.DueWebhookDelivery{
+		WebhookDelivery: *d,
+		URL:             sub.URL,
+		Secret:          sub.Secret,
+	}
+	if err := s.attemptDelivery(ctx, due); err != nil {
+		return nil, err
+	}
+	return s.GetWebhookDelivery(ctx, sub.ID, d.ID)
+}
+
+// GetWebhookDeliveries returns the latest deliveries to the webhook.
+func (s Service) GetWebhookDeliveries(ctx context.Context, uuid string) ([]model.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetSubscriptionByID(ctx, uuid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveries(ctx, uuid, webhookDeliveriesLimit)
+	if err != nil {
+		return nil, err
+	}
+	deliveriesModel := make([]model.WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		deliveriesModel[i].FromEntity(&d)
+	}
+	return deliveriesModel, nil
+}
+
+// GetWebhookDelivery returns the delivery along with all of its attempts.
+func (s Service) GetWebhookDelivery(ctx context.Context, webhookID, uuid string) (*model.WebhookDelivery, error) {
+	d, err := s.webhookRepo.GetDeliveryByID(ctx, webhookID, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := s.webhookRepo.GetAttempts(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(model.WebhookDelivery)
+	m.FromEntity(d)
+	m.AttemptLog = make([]model.WebhookDeliveryAttempt, len(attempts))
+	for i, a := range attempts {
+		m.AttemptLog[i].FromEntity(&a)
+	}
+	return m, nil
+}
+
+// ReplayWebhookDelivery schedules the delivery to be sent again with a full
+// set of retries, whatever its outcome was.
+func (s Service) ReplayWebhookDelivery(ctx context.Context, webhookID, uuid string) (*model.WebhookDelivery, error) {
+	d, err := s.webhookRepo.Replay(ctx, webhookID, uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := new(model.WebhookDelivery)
+	m.FromEntity(d)
+	return m, nil
+}
+
+// RunWebhookDispatcher periodically sends the due webhook deliveries until
+// done is closed.
+func (s Service) RunWebhookDispatcher(ctx context.Context, done chan bool) {
+	ticker := time.NewTicker(webhookDispatchInterval)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := s.dispatchWebhooks(ctx); err != nil {
+					log.Printf("failed to dispatch webhooks: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s Service) dispatchWebhooks(ctx context.Context) error {
+	deliveries, err := s.webhookRepo.ClaimDue(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d db.DueWebhookDelivery) {
+			defer wg.Done()
+			if err := s.attemptDelivery(ctx, d); err != nil {
+				log.Printf("failed to record webhook delivery %s: %v\n", d.ID, err)
+			}
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+// attemptDelivery posts the delivery to its webhook and records the outcome.
+func (s Service) attemptDelivery(ctx context.Context, d db.DueWebhookDelivery) error {
+	attempt, status, retryIn := sendDelivery(ctx, d)
+	return s.webhookRepo.RecordAttempt(ctx, attempt, status, retryIn)
+}
+
+// sendDelivery posts the delivery to its webhook and returns the attempt along
+// with the status the delivery moves to. Unless the webhook answers with 2xx,
+// the delivery is retried after retryIn with exponential backoff until
+// webhookMaxAttempts is reached.
+func sendDelivery(ctx context.Context, d db.DueWebhookDelivery) (attempt db.WebhookDeliveryAttempt, status string, retryIn time.Duration) {
+	start := time.Now()
+	statusCode, err := postWebhook(ctx, d)
+	attempt = db.WebhookDeliveryAttempt{
+		DeliveryID: d.ID,
+		StatusCode: statusCode,
+		DurationMs: int(time.Since(start).Milliseconds()),
+	}
+
+	status = db.WebhookDeliveryDelivered
+	if err != nil {
+		attempt.Error = err.Error()
+		status = db.WebhookDeliveryPending
+		retryIn = webhookBackoff(d.Attempts + 1)
+		if d.Attempts+1 >= webhookMaxAttempts {
+			status = db.WebhookDeliveryFailed
+		}
+		log.Printf("webhook delivery %s failed: %v\n", d.ID, err)
+	}
+	return attempt, status, retryIn
+}
+
+// postWebhook sends the delivery payload to its webhook. The error is non-nil
+// unless the webhook answers with 2xx.
+func postWebhook(ctx context.Context, d db.DueWebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, d.EventName)
+	req.Header.Set(WebhookDeliveryHeader, d.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(d.Secret, timestamp, d.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// drain the body, so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return &resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the signature a webhook receiver should compare
+// the X-Popug-Signature header against: HMAC-SHA256 of the timestamp and the
+// payload joined with a dot, keyed with the webhook secret.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns how long to wait before the next attempt after the
+// given number of failed ones.
+func webhookBackoff(failed int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < failed; i++ {
+		backoff *= 2
+		if backoff >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return backoff
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
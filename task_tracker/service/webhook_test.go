@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ko3luhbka/task_tracker/db"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    string
+	}{
+		{
+			name:    "payload",
+			payload: []byte(`{"name":"webhookTest"}`),
+			want:    "sha256=cd94c44a9956e6c7610dfe17f4bf466b16ba0f8ffd9711798af54a071e1c01b8",
+		},
+		{
+			name:    "empty payload",
+			payload: nil,
+			want:    "sha256=c8fd8d5443e4132e54cc3ceed514cd3d1cc70dbc3846e5616458b4128c6e6e3f",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhookPayload("secret", "1668000000", tt.payload); got != tt.want {
+				t.Errorf("SignWebhookPayload() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{failed: 1, want: webhookBaseBackoff},
+		{failed: 2, want: 2 * webhookBaseBackoff},
+		{failed: 3, want: 4 * webhookBaseBackoff},
+		{failed: 9, want: 256 * webhookBaseBackoff},
+		{failed: 10, want: webhookMaxBackoff},
+		{failed: 50, want: webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.failed); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %s, want %s", tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestSendDelivery(t *testing.T) {
+	const secret = "secret"
+	payload := []byte(`{"name":"webhookTest","data":{}}`)
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		attempts    int
+		wantStatus  string
+		wantRetryIn time.Duration
+	}{
+		{
+			name:       "delivered",
+			statusCode: http.StatusNoContent,
+			wantStatus: db.WebhookDeliveryDelivered,
+		},
+		{
+			name:        "first failure",
+			statusCode:  http.StatusInternalServerError,
+			wantStatus:  db.WebhookDeliveryPending,
+			wantRetryIn: webhookBaseBackoff,
+		},
+		{
+			name:        "redirect is a failure",
+			statusCode:  http.StatusNotModified,
+			attempts:    2,
+			wantStatus:  db.WebhookDeliveryPending,
+			wantRetryIn: 4 * webhookBaseBackoff,
+		},
+		{
+			name:        "last attempt",
+			statusCode:  http.StatusBadGateway,
+			attempts:    webhookMaxAttempts - 1,
+			wantStatus:  db.WebhookDeliveryFailed,
+			wantRetryIn: webhookBackoff(webhookMaxAttempts),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("failed to read request body: %v", err)
+				}
+				if string(body) != string(payload) {
+					t.Errorf("body = %s, want %s", body, payload)
+				}
+				if got := r.Header.Get(WebhookEventHeader); got != webhookTestEvent {
+					t.Errorf("%s = %s, want %s", WebhookEventHeader, got, webhookTestEvent)
+				}
+				if got := r.Header.Get(WebhookDeliveryHeader); got != "delivery-id" {
+					t.Errorf("%s = %s, want delivery-id", WebhookDeliveryHeader, got)
+				}
+				want := SignWebhookPayload(secret, r.Header.Get(WebhookTimestampHeader), body)
+				if got := r.Header.Get(WebhookSignatureHeader); got != want {
+					t.Errorf("%s = %s, want %s", WebhookSignatureHeader, got, want)
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer srv.Close()
+
+			d := db.DueWebhookDelivery{
+				WebhookDelivery: db.WebhookDelivery{
+					ID:        "delivery-id",
+					EventName: webhookTestEvent,
+					Payload:   payload,
+					Attempts:  tt.attempts,
+				},
+				URL:    srv.URL,
+				Secret: secret,
+			}
+			attempt, status, retryIn := sendDelivery(context.Background(), d)
+			if status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if retryIn != tt.wantRetryIn {
+				t.Errorf("retryIn = %s, want %s", retryIn, tt.wantRetryIn)
+			}
+			if attempt.DeliveryID != d.ID {
+				t.Errorf("attempt delivery id = %s, want %s", attempt.DeliveryID, d.ID)
+			}
+			if attempt.StatusCode == nil || *attempt.StatusCode != tt.statusCode {
+				t.Errorf("attempt status code = %v, want %d", attempt.StatusCode, tt.statusCode)
+			}
+			if (attempt.Error == "") != (tt.wantStatus == db.WebhookDeliveryDelivered) {
+				t.Errorf("attempt error = %q", attempt.Error)
+			}
+		})
+	}
+}
+
+func TestSendDeliveryUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	d := db.DueWebhookDelivery{
+		WebhookDelivery: db.WebhookDelivery{ID: "delivery-id", EventName: webhookTestEvent},
+		URL:             srv.URL,
+	}
+	attempt, status, retryIn := sendDelivery(context.Background(), d)
+	if status != db.WebhookDeliveryPending {
+		t.Errorf("status = %s, want %s", status, db.WebhookDeliveryPending)
+	}
+	if retryIn != webhookBaseBackoff {
+		t.Errorf("retryIn = %s, want %s", retryIn, webhookBaseBackoff)
+	}
+	if attempt.StatusCode != nil {
+		t.Errorf("attempt status code = %d, want none", *attempt.StatusCode)
+	}
+	if attempt.Error == "" {
+		t.Error("attempt error is empty")
+	}
+}
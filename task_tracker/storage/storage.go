@@ -0,0 +1,24 @@
+// Package storage keeps the binary content of task attachments.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores blobs under keys made up by the caller. Keys consist of
+// path segments separated by slashes, so that they can be used as file paths
+// and object storage keys alike.
+type BlobStore interface {
+	// Put stores the content read from r under key, replacing the existing one.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get returns the content stored under key or ErrNotFound. The caller
+	// must close the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the content stored under key. Removing a missing key is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}